@@ -3,6 +3,7 @@ package admin
 import (
 	"go.lumeweb.com/portal-plugin-admin/internal"
 	"go.lumeweb.com/portal-plugin-admin/internal/api"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
 	"go.lumeweb.com/portal-plugin-admin/internal/service"
 	"go.lumeweb.com/portal/core"
 )
@@ -14,6 +15,15 @@ func init() {
 			return api.NewAPI()
 		},
 		Depends: []string{"dashboard"},
+		Models: []any{
+			&models.SettingsPermission{},
+			&models.ScheduledSettingChange{},
+			&models.SettingsSnapshot{},
+			&models.SettingsProposal{},
+			&models.SettingsProtectedPrefix{},
+			&models.SettingsLock{},
+			&models.SettingsVersionKey{},
+		},
 		Services: func() ([]core.ServiceInfo, error) {
 			return []core.ServiceInfo{
 				{
@@ -29,7 +39,7 @@ func init() {
 					Factory: func() (core.Service, []core.ContextBuilderOption, error) {
 						return service.NewAdminSettingsService()
 					},
-					Depends: []string{core.CONFIG_SERVICE},
+					Depends: []string{core.CONFIG_SERVICE, core.CRON_SERVICE},
 				},
 			}, nil
 		},
@@ -46,32 +46,75 @@ func (a API) Configure(router *mux.Router, accessSvc core.AccessService) error {
 	accessMw := middleware.AccessMiddleware(a.ctx)
 
 	corsHandler := middleware.CorsMiddleware(&cors.Options{
-		ExposedHeaders: []string{"X-Total-Count"},
+		ExposedHeaders: []string{"X-Total-Count", "ETag"},
 	})
 
 	router.Use(corsHandler, authMw, accessMw)
 
+	// Read routes are open to operators as well, settings policies then scope what each admin may change
+	admins := []string{core.ACCESS_ADMIN_ROLE}
+	readers := []string{core.ACCESS_ADMIN_ROLE, service.ACCESS_OPERATOR_ROLE}
+
 	routes := []struct {
 		path    string
 		method  string
 		handler http.HandlerFunc
+		roles   []string
 	}{
-		{"/api/cron/jobs", "GET", a.handleListCronJobs},
-		{"/api/cron/jobs/{uuid}", "GET", a.handleGetCronJob},
-		{"/api/cron/jobs/{uuid}/logs", "GET", a.handleListCronJobLogs},
-		{"/api/cron/stats", "GET", a.handleGetCronStats},
-		{"/api/settings/schema", "GET", a.handleGetSchema},
-		{"/api/settings", "GET", a.handleListSettings},
-		{"/api/settings/{id}", "GET", a.handleGetSetting},
-		{"/api/settings/{id}", "POST", a.handleUpdateSetting},
+		{"/api/cron/jobs", "GET", a.handleListCronJobs, readers},
+		{"/api/cron/jobs/{uuid}", "GET", a.handleGetCronJob, readers},
+		{"/api/cron/jobs/{uuid}/logs", "GET", a.handleListCronJobLogs, readers},
+		{"/api/cron/stats", "GET", a.handleGetCronStats, readers},
+		{"/api/settings/schema", "GET", a.handleGetSchema, readers},
+		{"/api/settings/schema/{path}", "GET", a.handleGetSchemaPath, readers},
+		{"/api/settings", "GET", a.handleListSettings, readers},
+		{"/api/settings/validate", "POST", a.handleValidateSettings, admins},
+		{"/api/settings/export", "GET", a.handleExportSettings, readers},
+		{"/api/settings/import", "POST", a.handleImportSettings, admins},
+		{"/api/settings/reset", "POST", a.handleResetSettings, admins},
+		{"/api/settings/pending-restart", "GET", a.handleListPendingRestarts, readers},
+		{"/api/settings/events", "GET", a.handleSettingsEvents, readers},
+		{"/api/settings/permissions", "GET", a.handleListSettingsPermissions, admins},
+		{"/api/settings/permissions/{user_id}", "PUT", a.handleSetSettingsPermission, admins},
+		{"/api/settings/permissions/{user_id}", "DELETE", a.handleDeleteSettingsPermission, admins},
+		{"/api/settings/scheduled", "GET", a.handleListScheduledSettings, readers},
+		{"/api/settings/scheduled", "POST", a.handleCreateScheduledSetting, admins},
+		{"/api/settings/scheduled/{id}", "DELETE", a.handleCancelScheduledSetting, admins},
+		{"/api/settings/snapshots", "GET", a.handleListSnapshots, readers},
+		{"/api/settings/snapshots", "POST", a.handleCreateSnapshot, admins},
+		{"/api/settings/snapshots/{name}/diff", "GET", a.handleDiffSnapshot, readers},
+		{"/api/settings/snapshots/{name}/restore", "POST", a.handleRestoreSnapshot, admins},
+		{"/api/settings/probe/{group}", "POST", a.handleProbeSettings, admins},
+		{"/api/settings/proposals", "GET", a.handleListSettingsProposals, readers},
+		{"/api/settings/proposals", "POST", a.handleCreateSettingsProposal, admins},
+		{"/api/settings/proposals/protected", "GET", a.handleListProtectedPrefixes, readers},
+		{"/api/settings/proposals/protected", "PUT", a.handleSetProtectedPrefixes, admins},
+		{"/api/settings/proposals/{id}", "GET", a.handleGetSettingsProposal, readers},
+		{"/api/settings/proposals/{id}/approve", "POST", a.handleApproveSettingsProposal, admins},
+		{"/api/settings/proposals/{id}/reject", "POST", a.handleRejectSettingsProposal, admins},
+		{"/api/settings/locks", "GET", a.handleListSettingLocks, readers},
+		{"/api/settings/locks", "POST", a.handleLockSetting, admins},
+		{"/api/settings/locks/{key}", "DELETE", a.handleUnlockSetting, admins},
+		{"/api/settings/{id}", "GET", a.handleGetSetting, readers},
+		{"/api/settings/{id}", "POST", a.handleUpdateSetting, admins},
+		{"/api/settings/{id}", "PUT", a.handleSetSettingEntry, admins},
+		{"/api/settings/{id}", "DELETE", a.handleDeleteSettingEntry, admins},
+		{"/api/settings/{id}/reveal", "POST", a.handleRevealSetting, admins},
+		{"/api/settings/{id}/reset", "POST", a.handleResetSetting, admins},
+		{"/api/settings/{id}/layers", "GET", a.handleGetSettingLayers, readers},
+		{"/api/settings/{id}/items", "POST", a.handleInsertSettingItem, admins},
+		{"/api/settings/{id}/items/{index}", "DELETE", a.handleRemoveSettingItem, admins},
+		{"/api/settings/{id}/items/{index}/move", "POST", a.handleMoveSettingItem, admins},
 	}
 
 	subdomain := a.Subdomain()
 
 	for _, route := range routes {
 		router.HandleFunc(route.path, route.handler).Methods(route.method)
-		if err := accessSvc.RegisterRoute(subdomain, route.path, route.method, core.ACCESS_ADMIN_ROLE); err != nil {
-			return err
+		for _, role := range route.roles {
+			if err := accessSvc.RegisterRoute(subdomain, route.path, route.method, role); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -1,6 +1,9 @@
 package messages
 
-import "time"
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"time"
+)
 
 type ListCronJobsResponse = []CronJob
 
@@ -40,11 +43,251 @@ type PaginationData struct {
 }
 
 type SettingsItem struct {
-	Key      string `json:"key"`
-	Value    any    `json:"value"`
-	Editable bool   `json:"editable"`
+	Key             string          `json:"key"`
+	Value           any             `json:"value"`
+	Editable        bool            `json:"editable"`
+	Sensitive       bool            `json:"sensitive"`
+	RestartRequired bool            `json:"restart_required"`
+	Formatted       string          `json:"formatted,omitempty"`
+	Version         string          `json:"version"`
+	Source          string          `json:"source"`
+	Overridden      []*SettingLayer `json:"overridden,omitempty"`
+	Schema          *schema.Schema  `json:"schema,omitempty"`
+	Lock            *SettingLock    `json:"lock,omitempty"`
 }
 
 type SettingUpdateRequest struct {
 	Value any `json:"value"`
 }
+
+type SettingArrayInsertRequest struct {
+	Value any  `json:"value"`
+	Index *int `json:"index,omitempty"`
+}
+
+type SettingArrayMoveRequest struct {
+	To int `json:"to"`
+}
+
+// SettingArrayEdit is a single structural change to a list setting. Index is the element to insert
+// before, remove or move, and To is the position a moved element ends up at.
+type SettingArrayEdit struct {
+	Op    string `json:"op"`
+	Index int    `json:"index"`
+	To    int    `json:"to"`
+	Value any    `json:"value,omitempty"`
+}
+
+type SettingsValidateRequest struct {
+	Settings []SettingsValidateItem `json:"settings"`
+}
+
+type SettingsValidateItem struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+type SettingsValidateResponse struct {
+	Valid   bool                     `json:"valid"`
+	Results []SettingsValidateResult `json:"results"`
+}
+
+type SettingsValidateResult struct {
+	Key    string   `json:"key"`
+	Value  any      `json:"value,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type SettingsImportResponse struct {
+	DryRun  bool              `json:"dry_run"`
+	Changes []*SettingsChange `json:"changes"`
+}
+
+type SettingsChange struct {
+	Key      string   `json:"key"`
+	OldValue any      `json:"old_value"`
+	NewValue any      `json:"new_value"`
+	Editable bool     `json:"editable"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type SettingsResetRequest struct {
+	Prefix string `json:"prefix"`
+}
+
+type SettingsResetResponse struct {
+	Changes []*SettingsChange `json:"changes"`
+}
+
+type PendingRestartItem struct {
+	Key       string    `json:"key"`
+	OldValue  any       `json:"old_value"`
+	NewValue  any       `json:"new_value"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type SettingsVersionConflict struct {
+	Error   string `json:"error"`
+	Version string `json:"version"`
+}
+
+// SettingsValidationError is returned when validators registered by plugins reject a change.
+type SettingsValidationError struct {
+	Error  string   `json:"error"`
+	Key    string   `json:"key"`
+	Errors []string `json:"errors"`
+}
+
+type SettingsEvent struct {
+	Key       string    `json:"key"`
+	Value     any       `json:"value"`
+	Actor     uint      `json:"actor"`
+	Source    string    `json:"source"`
+	Removed   bool      `json:"removed,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type ListSettingsPermissionsResponse = []SettingsPermission
+
+type SettingsPermission struct {
+	UserID        uint      `json:"user_id"`
+	Role          string    `json:"role"`
+	WritePrefixes []string  `json:"write_prefixes"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type SettingsPermissionRequest struct {
+	Role          string   `json:"role"`
+	WritePrefixes []string `json:"write_prefixes"`
+}
+
+type SettingLayer struct {
+	Source string `json:"source"`
+	Value  any    `json:"value"`
+	Active bool   `json:"active"`
+}
+
+type SettingLayersResponse struct {
+	Key    string          `json:"key"`
+	Layers []*SettingLayer `json:"layers"`
+}
+
+type ListScheduledSettingChangesResponse = []ScheduledSettingChange
+
+type ScheduledSettingChange struct {
+	ID          uint       `json:"id"`
+	Key         string     `json:"key"`
+	Value       any        `json:"value"`
+	ApplyAt     time.Time  `json:"apply_at"`
+	RevertAfter string     `json:"revert_after,omitempty"`
+	RevertAt    *time.Time `json:"revert_at"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedBy   uint       `json:"created_by"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	RevertedAt  *time.Time `json:"reverted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateScheduledSettingChangeRequest struct {
+	Key         string     `json:"key"`
+	Value       any        `json:"value"`
+	ApplyAt     *time.Time `json:"apply_at"`
+	RevertAfter string     `json:"revert_after"`
+}
+
+type ListSettingsSnapshotsResponse = []SettingsSnapshot
+
+type SettingsSnapshot struct {
+	Name      string    `json:"name"`
+	Keys      int       `json:"keys"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateSettingsSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+type SettingsSnapshotDiffResponse struct {
+	From    string            `json:"from"`
+	To      string            `json:"to,omitempty"`
+	Changes []*SettingsChange `json:"changes"`
+}
+
+type SettingsProbeRequest struct {
+	Settings map[string]any `json:"settings"`
+	Timeout  string         `json:"timeout,omitempty"`
+}
+
+// SettingsProbeResponse reports each stage of a connectivity check. Durations are in milliseconds.
+type SettingsProbeResponse struct {
+	Group    string               `json:"group"`
+	Success  bool                 `json:"success"`
+	Duration int64                `json:"duration"`
+	Steps    []*SettingsProbeStep `json:"steps"`
+}
+
+type SettingsProbeStep struct {
+	Name     string `json:"name"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message,omitempty"`
+	Duration int64  `json:"duration"`
+}
+
+type ListSettingsProposalsResponse = []SettingsProposal
+
+type SettingsProposal struct {
+	ID         uint       `json:"id"`
+	Key        string     `json:"key"`
+	Value      any        `json:"value"`
+	Reason     string     `json:"reason,omitempty"`
+	Status     string     `json:"status"`
+	ProposedBy uint       `json:"proposed_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DecidedBy  *uint      `json:"decided_by"`
+	DecidedAt  *time.Time `json:"decided_at"`
+	Comment    string     `json:"comment,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type CreateSettingsProposalRequest struct {
+	Key       string `json:"key"`
+	Value     any    `json:"value"`
+	Reason    string `json:"reason"`
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+type DecideSettingsProposalRequest struct {
+	Comment string `json:"comment"`
+}
+
+type SettingsProtectedPrefixes struct {
+	Prefixes []string `json:"prefixes"`
+}
+
+type ListSettingLocksResponse = []*SettingLock
+
+// SettingLock describes a freeze on a setting. Key may be a prefix covering every setting under it.
+type SettingLock struct {
+	Key       string     `json:"key"`
+	Reason    string     `json:"reason"`
+	LockedBy  uint       `json:"locked_by"`
+	LockedAt  time.Time  `json:"locked_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type LockSettingRequest struct {
+	Key       string     `json:"key"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type SettingLockedResponse struct {
+	Error string       `json:"error"`
+	Lock  *SettingLock `json:"lock"`
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"go.lumeweb.com/httputil"
 	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
-	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,11 +16,35 @@ import (
 
 func (a *API) handleGetSchema(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
-	settings := a.settings.GetSchema()
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	settings := a.settings.GetSchemaFor(a.settings.AccessFor(userID))
 
 	ctx.Encode(settings)
 }
 
+func (a *API) handleGetSchemaPath(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	path := mux.Vars(r)["path"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	pathSchema := a.settings.GetSchemaPath(a.settings.AccessFor(userID), path)
+	if pathSchema == nil {
+		_ = ctx.Error(fmt.Errorf("Schema path not found"), http.StatusNotFound)
+		return
+	}
+
+	ctx.Encode(pathSchema)
+}
+
 func (a *API) handleListSettings(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 
@@ -27,9 +54,22 @@ func (a *API) handleListSettings(w http.ResponseWriter, r *http.Request) {
 	end, _ := strconv.Atoi(query.Get("_end"))
 	keyLike := query.Get("key_like")
 	valueLike := query.Get("value_like")
+	formatted := query.Get("formatted") == "true"
 
-	// Get all settings
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	// Get all settings, masking secrets before filtering so value_like cannot probe them
 	allSettings := a.settings.GetSettings()
+	a.settings.ApplyPermissions(a.settings.AccessFor(userID), allSettings...)
+	for _, setting := range allSettings {
+		a.settings.RedactSetting(setting)
+		if formatted {
+			a.settings.FormatSetting(setting)
+		}
+	}
 
 	// Filter settings
 	filteredSettings := filterSettings(allSettings, keyLike, valueLike)
@@ -40,21 +80,90 @@ func (a *API) handleListSettings(w http.ResponseWriter, r *http.Request) {
 
 	// Set Content-Range header
 	w.Header().Set("X-Total-Count", strconv.Itoa(totalCount))
+	w.Header().Set("ETag", formatETag(a.settings.GetSettingsVersion()))
 
 	ctx.Encode(paginatedSettings)
 }
 
+func (a *API) handleListPendingRestarts(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	ctx.Encode(a.settings.GetPendingRestarts())
+}
+
 func (a *API) handleGetSetting(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
 	setting := a.settings.GetSetting(id)
 	if setting == nil {
 		_ = ctx.Error(fmt.Errorf("Setting not found"), http.StatusNotFound)
 		return
 	}
 
+	access := a.settings.AccessFor(userID)
+	a.settings.ApplyPermissions(access, setting)
+	a.settings.RedactSetting(setting)
+	if r.URL.Query().Get("formatted") == "true" {
+		a.settings.FormatSetting(setting)
+	}
+	if r.URL.Query().Get("schema") == "true" {
+		setting.Schema = a.settings.GetSchemaPath(access, setting.Key)
+	}
+
+	w.Header().Set("ETag", formatETag(setting.Version))
+	ctx.Encode(setting)
+}
+
+func (a *API) handleGetSettingLayers(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	layers, err := a.settings.GetSettingLayers(id)
+	if errors.Is(err, service.ErrSettingNotFound) {
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	}
+	if ctx.Check("Failed to get setting layers", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingLayersResponse{
+		Key:    id,
+		Layers: layers,
+	})
+}
+
+func (a *API) handleRevealSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	setting, err := a.settings.RevealSetting(id, userID)
+	switch {
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
 	ctx.Encode(setting)
 }
 
@@ -74,12 +183,18 @@ func (a *API) handleUpdateSetting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	// Permissions are checked by the update itself
 	if !setting.Editable {
 		_ = ctx.Error(fmt.Errorf("Setting is not editable"), http.StatusForbidden)
 		return
 	}
 
-	setting, err := normalizeSetting(setting, data.Value)
+	setting, err = a.normalizeSetting(setting, data.Value)
 
 	// Verify the data type before updating
 	if err != nil {
@@ -87,20 +202,217 @@ func (a *API) handleUpdateSetting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.settings.UpdateSetting(
+	err = a.settings.UpdateSetting(userID,
 		&messages.SettingsItem{
-			Key:   setting.Key,
-			Value: data.Value,
-		}); err != nil {
+			Key:     setting.Key,
+			Value:   data.Value,
+			Version: r.Header.Get("If-Match"),
+		})
+	if writeSettingInvalid(w, err) || writeSettingLocked(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrVersionMismatch) {
+		a.writeSettingConflict(w, setting.Key)
+		return
+	}
+	if errors.Is(err, service.ErrPermissionDenied) || errors.Is(err, service.ErrApprovalRequired) {
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	}
+	if err != nil {
 		_ = ctx.Error(err, http.StatusInternalServerError)
 		return
 	}
 
+	if updated := a.settings.GetSetting(setting.Key); updated != nil {
+		w.Header().Set("ETag", formatETag(updated.Version))
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
-func normalizeSetting(setting *messages.SettingsItem, newValue any) (*messages.SettingsItem, error) {
-	normalized, err := internal.NormalizeSetting(setting.Value, newValue)
+func (a *API) handleValidateSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.SettingsValidateRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	if len(data.Settings) == 0 {
+		_ = ctx.Error(fmt.Errorf("No settings provided"), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	response := &messages.SettingsValidateResponse{
+		Valid:   true,
+		Results: make([]messages.SettingsValidateResult, len(data.Settings)),
+	}
+
+	for i, item := range data.Settings {
+		result := messages.SettingsValidateResult{Key: item.Key}
+
+		value, err := a.settings.ValidateSetting(userID, &messages.SettingsItem{
+			Key:   item.Key,
+			Value: item.Value,
+		})
+		var invalid *service.SettingValidationError
+		if errors.As(err, &invalid) {
+			result.Error = service.ErrSettingInvalid.Error()
+			result.Errors = invalid.Errors
+			response.Valid = false
+		} else if err != nil {
+			result.Error = err.Error()
+			response.Valid = false
+		} else {
+			result.Value = a.settings.RedactValue(item.Key, value)
+		}
+
+		response.Results[i] = result
+	}
+
+	ctx.Encode(response)
+}
+
+func (a *API) handleResetSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	err = a.settings.ResetSetting(userID, id, r.Header.Get("If-Match"))
+	if writeSettingInvalid(w, err) || writeSettingLocked(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrVersionMismatch):
+		a.writeSettingConflict(w, id)
+		return
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrSettingNotEditable), errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrApprovalRequired):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	setting := a.settings.GetSetting(id)
+	w.Header().Set("ETag", formatETag(setting.Version))
+	ctx.Encode(a.settings.RedactSetting(setting))
+}
+
+func (a *API) handleResetSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.SettingsResetRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	if data.Prefix == "" {
+		_ = ctx.Error(fmt.Errorf("Prefix is required"), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	changes, err := a.settings.ResetSettings(userID, data.Prefix, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrVersionMismatch) {
+		a.writeSettingsConflict(w)
+		return
+	}
+	if ctx.Check("Failed to reset settings", err) != nil {
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(a.settings.GetSettingsVersion()))
+	ctx.Encode(&messages.SettingsResetResponse{
+		Changes: changes,
+	})
+}
+
+// writeSettingConflict responds with 412 and the setting's current value and version.
+func (a *API) writeSettingConflict(w http.ResponseWriter, key string) {
+	setting := a.settings.GetSetting(key)
+	if setting == nil {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(setting.Version))
+	writeJSON(w, http.StatusPreconditionFailed, a.settings.RedactSetting(setting))
+}
+
+// writeSettingsConflict responds with 412 and the current version of the settings list.
+func (a *API) writeSettingsConflict(w http.ResponseWriter) {
+	version := a.settings.GetSettingsVersion()
+
+	w.Header().Set("ETag", formatETag(version))
+	writeJSON(w, http.StatusPreconditionFailed, &messages.SettingsVersionConflict{
+		Error:   service.ErrVersionMismatch.Error(),
+		Version: version,
+	})
+}
+
+// writeSettingInvalid responds with 422 and the validator messages if err is a rejection by a
+// registered validator, and reports whether it did.
+func writeSettingInvalid(w http.ResponseWriter, err error) bool {
+	var invalid *service.SettingValidationError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+
+	writeJSON(w, http.StatusUnprocessableEntity, &messages.SettingsValidationError{
+		Error:  service.ErrSettingInvalid.Error(),
+		Key:    invalid.Key,
+		Errors: invalid.Errors,
+	})
+	return true
+}
+
+// writeSettingLocked responds with 423 and the lock if err is caused by a locked setting, and reports
+// whether it did.
+func writeSettingLocked(w http.ResponseWriter, err error) bool {
+	var locked *service.SettingLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	writeJSON(w, http.StatusLocked, &messages.SettingLockedResponse{
+		Error: service.ErrSettingLocked.Error(),
+		Lock:  locked.Lock,
+	})
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func formatETag(version string) string {
+	return strconv.Quote(version)
+}
+
+func (a *API) normalizeSetting(setting *messages.SettingsItem, newValue any) (*messages.SettingsItem, error) {
+	normalized, err := a.settings.NormalizeSetting(setting.Key, setting.Value, newValue)
 	if err != nil {
 		return nil, err
 	}
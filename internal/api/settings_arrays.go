@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"strconv"
+)
+
+func (a *API) handleInsertSettingItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.SettingArrayInsertRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	edit := &messages.SettingArrayEdit{
+		Op:    service.ArrayEditAppend,
+		Value: data.Value,
+	}
+	if data.Index != nil {
+		edit.Op = service.ArrayEditInsert
+		edit.Index = *data.Index
+	}
+
+	a.editSettingArray(w, r, edit)
+}
+
+func (a *API) handleRemoveSettingItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid index"), http.StatusBadRequest)
+		return
+	}
+
+	a.editSettingArray(w, r, &messages.SettingArrayEdit{
+		Op:    service.ArrayEditRemove,
+		Index: index,
+	})
+}
+
+func (a *API) handleMoveSettingItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid index"), http.StatusBadRequest)
+		return
+	}
+
+	var data messages.SettingArrayMoveRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	a.editSettingArray(w, r, &messages.SettingArrayEdit{
+		Op:    service.ArrayEditMove,
+		Index: index,
+		To:    data.To,
+	})
+}
+
+// editSettingArray applies the edit to the list setting named in the route and responds with the updated setting.
+func (a *API) editSettingArray(w http.ResponseWriter, r *http.Request, edit *messages.SettingArrayEdit) {
+	ctx := httputil.Context(r, w)
+	id := mux.Vars(r)["id"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	err = a.settings.EditArraySetting(userID, id, edit, r.Header.Get("If-Match"))
+	if writeSettingInvalid(w, err) || writeSettingLocked(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrVersionMismatch):
+		a.writeSettingConflict(w, id)
+		return
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrSettingNotEditable), errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrApprovalRequired):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	setting := a.settings.GetSetting(id)
+	w.Header().Set("ETag", formatETag(setting.Version))
+	ctx.Encode(a.settings.RedactSetting(setting))
+}
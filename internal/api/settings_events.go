@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.lumeweb.com/httputil"
+	"net/http"
+	"time"
+)
+
+const settingsEventsHeartbeat = 30 * time.Second
+
+func (a *API) handleSettingsEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		_ = ctx.Error(fmt.Errorf("Streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := a.settings.SubscribeChanges()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(settingsEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: setting\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
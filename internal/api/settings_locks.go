@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+)
+
+func (a *API) handleListSettingLocks(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	locks, err := a.settings.ListSettingLocks()
+	if ctx.Check("Failed to list setting locks", err) != nil {
+		return
+	}
+
+	ctx.Encode(messages.ListSettingLocksResponse(locks))
+}
+
+func (a *API) handleLockSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.LockSettingRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	lock, err := a.settings.LockSetting(userID, data.Key, data.Reason, data.ExpiresAt)
+	switch {
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	ctx.Encode(lock)
+}
+
+func (a *API) handleUnlockSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	key := mux.Vars(r)["key"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	err = a.settings.UnlockSetting(userID, key)
+	switch {
+	case errors.Is(err, service.ErrSettingLockNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"strings"
+)
+
+// handleSetSettingEntry adds or replaces a single entry of a map setting, addressed by its dotted key.
+func (a *API) handleSetSettingEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	id := mux.Vars(r)["id"]
+
+	var data messages.SettingUpdateRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	created, err := a.settings.SetMapEntry(userID, id, data.Value, r.Header.Get("If-Match"))
+	if a.writeMapEntryError(w, r, id, err) {
+		return
+	}
+
+	setting := a.settings.GetSetting(id)
+	w.Header().Set("ETag", formatETag(setting.Version))
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, a.settings.RedactSetting(setting))
+}
+
+// handleDeleteSettingEntry removes a single entry of a map setting, addressed by its dotted key.
+func (a *API) handleDeleteSettingEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	id := mux.Vars(r)["id"]
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	err = a.settings.DeleteMapEntry(userID, id, r.Header.Get("If-Match"))
+	if a.writeMapEntryError(w, r, id, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMapEntryError responds to a failed map entry change and reports whether it did.
+func (a *API) writeMapEntryError(w http.ResponseWriter, r *http.Request, key string, err error) bool {
+	ctx := httputil.Context(r, w)
+
+	switch {
+	case err == nil:
+		return false
+	case writeSettingInvalid(w, err), writeSettingLocked(w, err):
+	case errors.Is(err, service.ErrVersionMismatch):
+		a.writeSettingConflict(w, key[:strings.LastIndex(key, ".")])
+	case errors.Is(err, service.ErrSettingNotFound), errors.Is(err, service.ErrMapEntryNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+	case errors.Is(err, service.ErrSettingNotEditable), errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrApprovalRequired):
+		_ = ctx.Error(err, http.StatusForbidden)
+	default:
+		_ = ctx.Error(err, http.StatusBadRequest)
+	}
+
+	return true
+}
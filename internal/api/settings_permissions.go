@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"strconv"
+)
+
+func (a *API) handleListSettingsPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	if !a.requireFullAccess(w, r) {
+		return
+	}
+
+	permissions, err := a.settings.ListPermissions()
+	if ctx.Check("Failed to list settings permissions", err) != nil {
+		return
+	}
+
+	response := make(messages.ListSettingsPermissionsResponse, len(permissions))
+	for i, permission := range permissions {
+		response[i] = toSettingsPermission(&permission)
+	}
+
+	ctx.Encode(response)
+}
+
+func (a *API) handleSetSettingsPermission(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	if !a.requireFullAccess(w, r) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid user ID"), http.StatusBadRequest)
+		return
+	}
+
+	var data messages.SettingsPermissionRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	permission, err := a.settings.SetPermission(uint(userID), data.Role, data.WritePrefixes)
+	if err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	ctx.Encode(toSettingsPermission(permission))
+}
+
+func (a *API) handleDeleteSettingsPermission(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	if !a.requireFullAccess(w, r) {
+		return
+	}
+
+	userID, err := strconv.ParseUint(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid user ID"), http.StatusBadRequest)
+		return
+	}
+
+	if ctx.Check("Failed to delete settings permission", a.settings.DeletePermission(uint(userID))) != nil {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireFullAccess only lets admins without a settings policy through, so restricted admins cannot
+// widen their own permissions.
+func (a *API) requireFullAccess(w http.ResponseWriter, r *http.Request) bool {
+	ctx := httputil.Context(r, w)
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return false
+	}
+
+	if !a.settings.HasFullAccess(userID) {
+		_ = ctx.Error(fmt.Errorf("Permission denied"), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func toSettingsPermission(permission *models.SettingsPermission) messages.SettingsPermission {
+	return messages.SettingsPermission{
+		UserID:        permission.UserID,
+		Role:          permission.Role,
+		WritePrefixes: permission.WritePrefixes,
+		UpdatedAt:     permission.UpdatedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"time"
+)
+
+func (a *API) handleProbeSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	group := mux.Vars(r)["group"]
+
+	var data messages.SettingsProbeRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	var timeout time.Duration
+	if data.Timeout != "" {
+		parsed, err := time.ParseDuration(data.Timeout)
+		if err != nil {
+			_ = ctx.Error(fmt.Errorf("Invalid timeout"), http.StatusBadRequest)
+			return
+		}
+		timeout = parsed
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	result, err := a.settings.ProbeSettings(userID, group, data.Settings, timeout)
+	if writeSettingInvalid(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrUnknownSettingsProbe):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Encode(result)
+}
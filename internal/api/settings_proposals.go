@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (a *API) handleListSettingsProposals(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	proposals, err := a.settings.ListSettingsProposals(r.URL.Query().Get("status"))
+	if ctx.Check("Failed to list setting proposals", err) != nil {
+		return
+	}
+
+	response := make(messages.ListSettingsProposalsResponse, len(proposals))
+	for i, proposal := range proposals {
+		response[i] = a.toSettingsProposal(&proposal)
+	}
+
+	ctx.Encode(response)
+}
+
+func (a *API) handleCreateSettingsProposal(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.CreateSettingsProposalRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	var ttl time.Duration
+	if data.ExpiresIn != "" {
+		ttl, err = time.ParseDuration(data.ExpiresIn)
+		if err != nil {
+			_ = ctx.Error(fmt.Errorf("Invalid expires_in: %w", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	proposal, err := a.settings.ProposeSettingChange(userID, data.Key, data.Value, data.Reason, ttl)
+	if writeSettingInvalid(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrSettingNotEditable), errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a.toSettingsProposal(proposal))
+}
+
+func (a *API) handleGetSettingsProposal(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, ok := proposalID(w, r)
+	if !ok {
+		return
+	}
+
+	proposal, err := a.settings.GetSettingsProposal(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = ctx.Error(fmt.Errorf("Proposal not found"), http.StatusNotFound)
+		return
+	}
+	if ctx.Check("Failed to get setting proposal", err) != nil {
+		return
+	}
+
+	ctx.Encode(a.toSettingsProposal(proposal))
+}
+
+func (a *API) handleApproveSettingsProposal(w http.ResponseWriter, r *http.Request) {
+	a.decideSettingsProposal(w, r, a.settings.ApproveSettingsProposal)
+}
+
+func (a *API) handleRejectSettingsProposal(w http.ResponseWriter, r *http.Request) {
+	a.decideSettingsProposal(w, r, a.settings.RejectSettingsProposal)
+}
+
+func (a *API) decideSettingsProposal(w http.ResponseWriter, r *http.Request, decide func(actor uint, id uint, comment string) (*models.SettingsProposal, error)) {
+	ctx := httputil.Context(r, w)
+
+	id, ok := proposalID(w, r)
+	if !ok {
+		return
+	}
+
+	var data messages.DecideSettingsProposalRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	proposal, err := decide(userID, id, data.Comment)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = ctx.Error(fmt.Errorf("Proposal not found"), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied), errors.Is(err, service.ErrSelfApproval):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrProposalNotPending):
+		_ = ctx.Error(err, http.StatusConflict)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Encode(a.toSettingsProposal(proposal))
+}
+
+func (a *API) handleListProtectedPrefixes(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	prefixes, err := a.settings.ListProtectedPrefixes()
+	if ctx.Check("Failed to list protected prefixes", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingsProtectedPrefixes{Prefixes: prefixes})
+}
+
+func (a *API) handleSetProtectedPrefixes(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.SettingsProtectedPrefixes
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	prefixes, err := a.settings.SetProtectedPrefixes(userID, data.Prefixes)
+	if errors.Is(err, service.ErrPermissionDenied) || errors.Is(err, service.ErrApprovalRequired) {
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	}
+	if ctx.Check("Failed to set protected prefixes", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingsProtectedPrefixes{Prefixes: prefixes})
+}
+
+func proposalID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		_ = httputil.Context(r, w).Error(fmt.Errorf("Invalid ID"), http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (a *API) toSettingsProposal(proposal *models.SettingsProposal) messages.SettingsProposal {
+	return messages.SettingsProposal{
+		ID:         proposal.ID,
+		Key:        proposal.Key,
+		Value:      a.settings.RedactValue(proposal.Key, proposal.Value),
+		Reason:     proposal.Reason,
+		Status:     proposal.Status,
+		ProposedBy: proposal.ProposedBy,
+		CreatedAt:  proposal.CreatedAt,
+		ExpiresAt:  proposal.ExpiresAt,
+		DecidedBy:  proposal.DecidedBy,
+		DecidedAt:  proposal.DecidedAt,
+		Comment:    proposal.Comment,
+		Error:      proposal.Error,
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (a *API) handleListScheduledSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	changes, err := a.settings.ListScheduledSettingChanges(r.URL.Query().Get("all") == "true")
+	if ctx.Check("Failed to list scheduled setting changes", err) != nil {
+		return
+	}
+
+	response := make(messages.ListScheduledSettingChangesResponse, len(changes))
+	for i, change := range changes {
+		response[i] = a.toScheduledSettingChange(&change)
+	}
+
+	ctx.Encode(response)
+}
+
+func (a *API) handleCreateScheduledSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.CreateScheduledSettingChangeRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	applyAt := time.Now()
+	if data.ApplyAt != nil {
+		applyAt = *data.ApplyAt
+	}
+
+	var revertAfter time.Duration
+	if data.RevertAfter != "" {
+		revertAfter, err = time.ParseDuration(data.RevertAfter)
+		if err != nil {
+			_ = ctx.Error(fmt.Errorf("Invalid revert_after: %w", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	change, err := a.settings.ScheduleSettingChange(userID, data.Key, data.Value, applyAt, revertAfter)
+	switch {
+	case errors.Is(err, service.ErrSettingNotFound):
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrSettingNotEditable), errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrApprovalRequired):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	ctx.Encode(a.toScheduledSettingChange(change))
+}
+
+func (a *API) handleCancelScheduledSetting(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid ID"), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	change, err := a.settings.CancelScheduledSettingChange(userID, uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = ctx.Error(fmt.Errorf("Scheduled change not found"), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrPermissionDenied):
+		_ = ctx.Error(err, http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrScheduledChangeNotCancellable):
+		_ = ctx.Error(err, http.StatusConflict)
+		return
+	case err != nil:
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Encode(a.toScheduledSettingChange(change))
+}
+
+func (a *API) toScheduledSettingChange(change *models.ScheduledSettingChange) messages.ScheduledSettingChange {
+	response := messages.ScheduledSettingChange{
+		ID:         change.ID,
+		Key:        change.Key,
+		Value:      a.settings.RedactValue(change.Key, change.Value),
+		ApplyAt:    change.ApplyAt,
+		RevertAt:   change.RevertAt,
+		Status:     change.Status,
+		Error:      change.Error,
+		CreatedBy:  change.CreatedBy,
+		AppliedAt:  change.AppliedAt,
+		RevertedAt: change.RevertedAt,
+		CreatedAt:  change.CreatedAt,
+	}
+
+	if change.RevertAfter > 0 {
+		response.RevertAfter = change.RevertAfter.String()
+	}
+
+	return response
+}
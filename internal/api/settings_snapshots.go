@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+)
+
+func (a *API) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	snapshots, err := a.settings.ListSnapshots()
+	if ctx.Check("Failed to list snapshots", err) != nil {
+		return
+	}
+
+	response := make(messages.ListSettingsSnapshotsResponse, len(snapshots))
+	for i, snapshot := range snapshots {
+		response[i] = messages.SettingsSnapshot{
+			Name:      snapshot.Name,
+			Keys:      len(snapshot.Values),
+			CreatedBy: snapshot.CreatedBy,
+			CreatedAt: snapshot.CreatedAt,
+		}
+	}
+
+	ctx.Encode(response)
+}
+
+func (a *API) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var data messages.CreateSettingsSnapshotRequest
+	if err := ctx.Decode(&data); err != nil {
+		return
+	}
+
+	if data.Name == "" {
+		_ = ctx.Error(fmt.Errorf("Name is required"), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	snapshot, err := a.settings.CreateSnapshot(userID, data.Name)
+	if errors.Is(err, service.ErrSnapshotExists) {
+		_ = ctx.Error(err, http.StatusConflict)
+		return
+	}
+	if ctx.Check("Failed to create snapshot", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingsSnapshot{
+		Name:      snapshot.Name,
+		Keys:      len(snapshot.Values),
+		CreatedBy: snapshot.CreatedBy,
+		CreatedAt: snapshot.CreatedAt,
+	})
+}
+
+func (a *API) handleDiffSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	name := mux.Vars(r)["name"]
+	against := r.URL.Query().Get("against")
+
+	changes, err := a.settings.DiffSnapshot(name, against)
+	if errors.Is(err, service.ErrSnapshotNotFound) {
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	}
+	if ctx.Check("Failed to diff snapshot", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingsSnapshotDiffResponse{
+		From:    name,
+		To:      against,
+		Changes: changes,
+	})
+}
+
+func (a *API) handleRestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	name := mux.Vars(r)["name"]
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	changes, err := a.settings.RestoreSnapshot(userID, name, dryRun)
+	if errors.Is(err, service.ErrSnapshotNotFound) {
+		_ = ctx.Error(err, http.StatusNotFound)
+		return
+	}
+	if ctx.Check("Failed to restore snapshot", err) != nil {
+		return
+	}
+
+	ctx.Encode(&messages.SettingsImportResponse{
+		DryRun:  dryRun,
+		Changes: changes,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/service"
+	"go.lumeweb.com/portal/middleware"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+)
+
+const maxImportSize = 10 << 20
+
+func (a *API) handleExportSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "yaml"
+	}
+
+	doc := a.settings.ExportSettings()
+
+	var (
+		data        []byte
+		err         error
+		contentType string
+	)
+
+	switch format {
+	case "yaml":
+		data, err = yaml.Marshal(doc)
+		contentType = "application/yaml"
+	case "json":
+		data, err = json.MarshalIndent(doc, "", "  ")
+		contentType = "application/json"
+	default:
+		_ = ctx.Error(fmt.Errorf("Unsupported format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	if ctx.Check("Failed to export settings", err) != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"config.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func (a *API) handleImportSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		_ = ctx.Error(fmt.Errorf("Failed to read import document: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	// YAML is a superset of JSON, so both export formats decode the same way
+	var doc map[string]any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		_ = ctx.Error(fmt.Errorf("Invalid import document: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(doc) == 0 {
+		_ = ctx.Error(fmt.Errorf("Import document is empty"), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	userID, err := middleware.GetUserFromContext(r.Context())
+	if ctx.Check("Failed to get user", err) != nil {
+		return
+	}
+
+	changes, err := a.settings.ImportSettings(userID, doc, dryRun, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrVersionMismatch) {
+		a.writeSettingsConflict(w)
+		return
+	}
+	if ctx.Check("Failed to import settings", err) != nil {
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(a.settings.GetSettingsVersion()))
+	ctx.Encode(&messages.SettingsImportResponse{
+		DryRun:  dryRun,
+		Changes: changes,
+	})
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	ScheduledSettingChangePending   = "pending"
+	ScheduledSettingChangeApplied   = "applied"
+	ScheduledSettingChangeReverted  = "reverted"
+	ScheduledSettingChangeCancelled = "cancelled"
+	ScheduledSettingChangeFailed    = "failed"
+)
+
+// ScheduledSettingChange is a setting change applied at a set time, optionally reverted after a duration.
+type ScheduledSettingChange struct {
+	gorm.Model
+	Key            string    `gorm:"index"`
+	Value          any       `gorm:"serializer:json"`
+	PreviousValue  any       `gorm:"serializer:json"`
+	AppliedVersion string    `gorm:"size:64"`
+	ApplyAt        time.Time `gorm:"index"`
+	RevertAfter    time.Duration
+	RevertAt       *time.Time `gorm:"index"`
+	Status         string     `gorm:"size:16;index"`
+	Error          string
+	CreatedBy      uint
+	AppliedAt      *time.Time
+	RevertedAt     *time.Time
+}
+
+func (ScheduledSettingChange) TableName() string {
+	return "admin_scheduled_setting_changes"
+}
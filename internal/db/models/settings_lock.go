@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SettingsLock freezes a setting, or every setting under a prefix, against changes until it is removed or expires.
+type SettingsLock struct {
+	gorm.Model
+	Key       string `gorm:"uniqueIndex;size:255"`
+	Reason    string
+	CreatedBy uint
+	ExpiresAt *time.Time `gorm:"index"`
+}
+
+func (SettingsLock) TableName() string {
+	return "admin_settings_locks"
+}
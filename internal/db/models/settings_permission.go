@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// SettingsPermission narrows which settings an admin may change. Admins without a record keep full access.
+type SettingsPermission struct {
+	gorm.Model
+	UserID        uint     `gorm:"uniqueIndex"`
+	Role          string   `gorm:"size:32"`
+	WritePrefixes []string `gorm:"serializer:json"`
+}
+
+func (SettingsPermission) TableName() string {
+	return "admin_settings_permissions"
+}
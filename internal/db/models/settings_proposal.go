@@ -0,0 +1,35 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	SettingsProposalPending  = "pending"
+	SettingsProposalApplying = "applying"
+	SettingsProposalApproved = "approved"
+	SettingsProposalRejected = "rejected"
+	SettingsProposalExpired  = "expired"
+	SettingsProposalFailed   = "failed"
+)
+
+// SettingsProposal is a change to a protected setting that is applied once a second admin approves it.
+type SettingsProposal struct {
+	gorm.Model
+	Key        string `gorm:"index"`
+	Value      any    `gorm:"serializer:json"`
+	Version    string `gorm:"size:64"`
+	Reason     string
+	Status     string    `gorm:"size:16;index"`
+	ProposedBy uint      `gorm:"index"`
+	ExpiresAt  time.Time `gorm:"index"`
+	DecidedBy  *uint
+	DecidedAt  *time.Time
+	Comment    string
+	Error      string
+}
+
+func (SettingsProposal) TableName() string {
+	return "admin_settings_proposals"
+}
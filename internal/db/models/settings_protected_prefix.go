@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// SettingsProtectedPrefix marks settings under the prefix as needing an approved proposal to change.
+type SettingsProtectedPrefix struct {
+	gorm.Model
+	Prefix    string `gorm:"uniqueIndex;size:255"`
+	CreatedBy uint
+}
+
+func (SettingsProtectedPrefix) TableName() string {
+	return "admin_settings_protected_prefixes"
+}
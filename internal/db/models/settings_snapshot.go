@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// SettingsSnapshot is a named copy of the editable settings, keyed by dotted setting key.
+type SettingsSnapshot struct {
+	gorm.Model
+	Name      string         `gorm:"uniqueIndex;size:128"`
+	Values    map[string]any `gorm:"serializer:json"`
+	CreatedBy uint
+}
+
+func (SettingsSnapshot) TableName() string {
+	return "admin_settings_snapshots"
+}
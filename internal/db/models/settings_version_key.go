@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// SettingsVersionKey holds the key setting versions are derived from. There is a single row, shared by
+// every node, so versions stay the same across restarts and cluster members.
+type SettingsVersionKey struct {
+	gorm.Model
+	Key string `gorm:"size:64"`
+}
+
+func (SettingsVersionKey) TableName() string {
+	return "admin_settings_version_keys"
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"time"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+)
+
+// EncodeSetting converts a setting value into the plain form the config file uses, so it can be stored or
+// exported and later read back by NormalizeSetting. Structs become maps keyed by their config names and
+// durations become strings such as "1h30m0s". Types that marshal themselves are kept as they are.
+func EncodeSetting(value any) any {
+	if value == nil {
+		return nil
+	}
+	return encodeValue(reflect.ValueOf(value))
+}
+
+func encodeValue(value reflect.Value) any {
+	if value.Type() == durationType {
+		return time.Duration(value.Int()).String()
+	}
+	if marshalsItself(value.Type()) {
+		return value.Interface()
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return encodeValue(value.Elem())
+	case reflect.Struct:
+		doc := make(map[string]any)
+		for name, index := range StructFields(value.Type()) {
+			if name == "-" {
+				continue
+			}
+			doc[name] = encodeValue(value.FieldByIndex(index))
+		}
+		return doc
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are kept whole rather than turned into lists of numbers
+			return value.Interface()
+		}
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = encodeValue(value.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		doc := make(map[string]any, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			doc[fmt.Sprint(iter.Key().Interface())] = encodeValue(iter.Value())
+		}
+		return doc
+	default:
+		return value.Interface()
+	}
+}
+
+// marshalsItself reports whether the type controls its own encoding, in which case its fields are not
+// walked.
+func marshalsItself(t reflect.Type) bool {
+	if t == reflect.TypeOf(time.Time{}) {
+		return true
+	}
+	for _, marshaler := range []reflect.Type{jsonMarshalerType, textMarshalerType, yamlMarshalerType} {
+		if t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// exportTestSettings mirrors the flattened keys of a running config.
+func exportTestSettings() map[string]any {
+	return map[string]any{
+		"core.domain":         "example.com",
+		"core.port":           uint16(8080),
+		"core.max_size":       int64(math.MaxInt64),
+		"core.ratio":          1.0,
+		"core.enabled":        true,
+		"core.db.timeout":     90 * time.Minute,
+		"core.db.retry_delay": 1500 * time.Millisecond,
+		"core.tags":           []string{"a", "b"},
+		"core.empty_tags":     []string(nil),
+		"core.backends": []normalizeBackend{
+			{Name: "main", Port: 80, Timeout: 5 * time.Second, Tags: []string{"x"}},
+			{Name: "spare", Port: 81},
+		},
+		// The embedded Listener.Name is shadowed and has no config key of its own
+		"core.listeners": []normalizeEmbedded{
+			{Listener: Listener{Port: 443}, Name: "outer", Weight: 2},
+		},
+	}
+}
+
+func TestEncodeSettingStruct(t *testing.T) {
+	got := EncodeSetting(normalizeEmbedded{Listener: Listener{Name: "inner", Port: 443}, Name: "outer", Weight: 2})
+	want := map[string]any{"name": "outer", "port": uint16(443), "weight": 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("EncodeSetting = %#v, want %#v", got, want)
+	}
+
+	if got := EncodeSetting(90 * time.Minute); got != "1h30m0s" {
+		t.Fatalf("EncodeSetting(90m) = %#v, want 1h30m0s", got)
+	}
+}
+
+// TestExportImportRoundTrip exports settings the way ExportSettings does, decodes the document the way
+// the import handler does and checks every key normalizes back to the value it came from.
+func TestExportImportRoundTrip(t *testing.T) {
+	formats := map[string]func(any) ([]byte, error){
+		"json": func(doc any) ([]byte, error) { return json.MarshalIndent(doc, "", "  ") },
+		"yaml": yaml.Marshal,
+	}
+
+	for format, marshal := range formats {
+		t.Run(format, func(t *testing.T) {
+			settings := exportTestSettings()
+
+			exported := make(map[string]any, len(settings))
+			for key, value := range settings {
+				exported[key] = EncodeSetting(value)
+			}
+
+			data, err := marshal(NestSettings(exported))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc map[string]any
+			if err := yaml.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			imported := FlattenSettings(doc)
+			if len(imported) != len(settings) {
+				t.Fatalf("imported %d keys, want %d", len(imported), len(settings))
+			}
+
+			for key, current := range settings {
+				normalized, err := NormalizeSetting(current, imported[key])
+				if err != nil {
+					t.Fatalf("%s: %v", key, err)
+				}
+				if !reflect.DeepEqual(normalized, current) {
+					t.Fatalf("%s changed: %#v, want %#v", key, normalized, current)
+				}
+			}
+		})
+	}
+}
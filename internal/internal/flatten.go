@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+// FlattenSettings converts a nested settings document into dotted keys. Slices are kept as leaf values.
+func FlattenSettings(doc map[string]any) map[string]any {
+	flat := make(map[string]any)
+	flattenInto(flat, "", doc)
+	return flat
+}
+
+func flattenInto(flat map[string]any, prefix string, doc map[string]any) {
+	for key, value := range doc {
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenInto(flat, fullKey, nested)
+			continue
+		}
+
+		flat[fullKey] = value
+	}
+}
+
+// NestSettings converts dotted keys into a nested settings document, matching the config file layout.
+func NestSettings(flat map[string]any) map[string]any {
+	doc := make(map[string]any)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		current := doc
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := current[part].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				current[part] = next
+			}
+			current = next
+		}
+		current[parts[len(parts)-1]] = flat[key]
+	}
+
+	return doc
+}
@@ -1,52 +1,451 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
+	"strconv"
 	"time"
 )
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
 // NormalizeSetting checks and converts the newValue to the appropriate type
 func NormalizeSetting(currentValue interface{}, newValue interface{}) (interface{}, error) {
-	switch currentValue.(type) {
-	case string:
-		if strValue, ok := newValue.(string); ok {
-			return strValue, nil
-		}
-	case int:
-		if intValue, ok := newValue.(int); ok {
-			return intValue, nil
-		}
-	case float64:
-		if floatValue, ok := newValue.(float64); ok {
-			return floatValue, nil
-		}
-	case bool:
-		if boolValue, ok := newValue.(bool); ok {
-			return boolValue, nil
-		}
-	case time.Duration:
-		switch v := newValue.(type) {
-		case time.Duration:
-			return v, nil
-		case string:
-			parsedDuration, err := time.ParseDuration(v)
+	if currentValue == nil {
+		// Nothing to infer a type from, so accept the value as given
+		return newValue, nil
+	}
+
+	if newValue == nil {
+		if t := reflect.TypeOf(currentValue); canBeNil(t) {
+			// An empty list or map is written out as null
+			return reflect.Zero(t).Interface(), nil
+		}
+		return nil, fmt.Errorf("type mismatch: cannot set nil to %T", currentValue)
+	}
+
+	normalized, err := coerceValue(reflect.TypeOf(currentValue), reflect.ValueOf(newValue))
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized.Interface(), nil
+}
+
+// NormalizeSettingWithFormat behaves like NormalizeSetting, but also accepts human-friendly strings for
+// settings whose schema declares a unit format, such as "10GiB" for bytes or "1h30m" for durations.
+func NormalizeSettingWithFormat(currentValue interface{}, newValue interface{}, format string) (interface{}, error) {
+	if str, ok := newValue.(string); ok && currentValue != nil {
+		switch format {
+		case FormatBytes:
+			size, err := ParseSize(str)
 			if err != nil {
-				return nil, fmt.Errorf("invalid duration format: %v", err)
+				return nil, err
+			}
+			newValue = size
+		case FormatDuration:
+			if reflect.TypeOf(currentValue) != durationType {
+				duration, err := time.ParseDuration(str)
+				if err != nil {
+					return nil, fmt.Errorf("invalid duration format: %v", err)
+				}
+				newValue = int64(duration)
+			}
+		}
+	}
+
+	return NormalizeSetting(currentValue, newValue)
+}
+
+// FormatSetting renders a value in the human-friendly form of its schema format. It returns false when the
+// format has no representation or the value is not numeric.
+func FormatSetting(value interface{}, format string) (string, bool) {
+	v := reflect.ValueOf(value)
+
+	var n int64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			if format == FormatBytes {
+				return FormatSize(v.Uint()), true
 			}
-			return parsedDuration, nil
-		case float64:
-			return time.Duration(v * float64(time.Second)), nil
-		default:
-			return nil, fmt.Errorf("invalid data type for duration: expected string, float64, or time.Duration")
+			return "", false
+		}
+		n = int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = int64(v.Float())
+	default:
+		return "", false
+	}
+
+	switch format {
+	case FormatBytes:
+		if n < 0 {
+			return "", false
+		}
+		return FormatSize(uint64(n)), true
+	case FormatDuration:
+		return time.Duration(n).String(), true
+	}
+
+	return "", false
+}
+
+// coerceValue converts value to the target type, recursing into slices, arrays and maps element by element.
+func coerceValue(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	// Look through interface wrappers such as the elements of []interface{}
+	for value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || (value.Kind() == reflect.Interface && value.IsNil()) {
+		if canBeNil(target) {
+			return reflect.Zero(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set nil to %s", target)
+	}
+
+	if target == durationType {
+		return coerceDuration(value)
+	}
+
+	if value.Type() == target {
+		return value, nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if value.Type().Implements(target) {
+			return value.Convert(target), nil
+		}
+	case reflect.String:
+		if value.Kind() == reflect.String {
+			return value.Convert(target), nil
+		}
+	case reflect.Bool:
+		if value.Kind() == reflect.Bool {
+			return value.Convert(target), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return coerceInt(target, value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return coerceUint(target, value)
+	case reflect.Float32, reflect.Float64:
+		return coerceFloat(target, value)
+	case reflect.Slice:
+		return coerceSlice(target, value)
+	case reflect.Array:
+		return coerceArray(target, value)
+	case reflect.Map:
+		return coerceMap(target, value)
+	case reflect.Struct:
+		if value.Kind() == reflect.Map {
+			return coerceStruct(target, value)
+		}
+	case reflect.Ptr:
+		elem, err := coerceValue(target.Elem(), value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(target.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	if value.Type().AssignableTo(target) {
+		return value, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+}
+
+// coerceDuration accepts duration strings such as "1h30m" and numbers, which count seconds.
+func coerceDuration(value reflect.Value) (reflect.Value, error) {
+	if number, ok := value.Interface().(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			return durationFromSeconds(n)
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid duration: %v", err)
+		}
+		return durationFromFloat(f)
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == durationType {
+			return value, nil
+		}
+		return durationFromSeconds(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return reflect.Value{}, fmt.Errorf("duration of %d seconds overflows time.Duration", value.Uint())
+		}
+		return durationFromSeconds(int64(value.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return durationFromFloat(value.Float())
+	case reflect.String:
+		parsedDuration, err := time.ParseDuration(value.String())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid duration format: %v", err)
+		}
+		return reflect.ValueOf(parsedDuration), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("invalid data type for duration: expected string, number of seconds, or time.Duration")
+	}
+}
+
+// durationFromSeconds converts a whole number of seconds without losing precision to float rounding.
+func durationFromSeconds(n int64) (reflect.Value, error) {
+	if n > math.MaxInt64/int64(time.Second) || n < math.MinInt64/int64(time.Second) {
+		return reflect.Value{}, fmt.Errorf("duration of %d seconds overflows time.Duration", n)
+	}
+	return reflect.ValueOf(time.Duration(n) * time.Second), nil
+}
+
+func durationFromFloat(f float64) (reflect.Value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return reflect.Value{}, fmt.Errorf("invalid duration: %v", f)
+	}
+	nanos := f * float64(time.Second)
+	if nanos < math.MinInt64 || nanos >= math.MaxInt64 {
+		return reflect.Value{}, fmt.Errorf("duration of %v seconds overflows time.Duration", f)
+	}
+	return reflect.ValueOf(time.Duration(nanos)), nil
+}
+
+func coerceInt(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	var n int64
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := value.Uint()
+		if u > math.MaxInt64 {
+			return reflect.Value{}, fmt.Errorf("value %d overflows %s", u, target)
+		}
+		n = int64(u)
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) || math.IsNaN(f) {
+			return reflect.Value{}, fmt.Errorf("value %v is not an integer", f)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return reflect.Value{}, fmt.Errorf("value %v overflows %s", f, target)
+		}
+		n = int64(f)
+	case reflect.String:
+		number, ok := value.Interface().(json.Number)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
 		}
-	case []interface{}:
-		if arrayValue, ok := newValue.([]interface{}); ok {
-			return arrayValue, nil
+		parsed, err := strconv.ParseInt(number.String(), 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("value %s is not a valid %s", number, target)
 		}
-	case map[string]interface{}:
-		if mapValue, ok := newValue.(map[string]interface{}); ok {
-			return mapValue, nil
+		n = parsed
+	default:
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	result := reflect.New(target).Elem()
+	if result.OverflowInt(n) {
+		return reflect.Value{}, fmt.Errorf("value %d overflows %s", n, target)
+	}
+	result.SetInt(n)
+
+	return result, nil
+}
+
+func coerceUint(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	var n uint64
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := value.Int()
+		if i < 0 {
+			return reflect.Value{}, fmt.Errorf("value %d is negative, expected %s", i, target)
+		}
+		n = uint64(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n = value.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) || math.IsNaN(f) {
+			return reflect.Value{}, fmt.Errorf("value %v is not an integer", f)
+		}
+		if f < 0 {
+			return reflect.Value{}, fmt.Errorf("value %v is negative, expected %s", f, target)
+		}
+		if f >= math.MaxUint64 {
+			return reflect.Value{}, fmt.Errorf("value %v overflows %s", f, target)
+		}
+		n = uint64(f)
+	case reflect.String:
+		number, ok := value.Interface().(json.Number)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+		}
+		parsed, err := strconv.ParseUint(number.String(), 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("value %s is not a valid %s", number, target)
+		}
+		n = parsed
+	default:
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	result := reflect.New(target).Elem()
+	if result.OverflowUint(n) {
+		return reflect.Value{}, fmt.Errorf("value %d overflows %s", n, target)
+	}
+	result.SetUint(n)
+
+	return result, nil
+}
+
+func coerceFloat(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	var f float64
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = value.Float()
+	case reflect.String:
+		number, ok := value.Interface().(json.Number)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
 		}
+		parsed, err := number.Float64()
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("value %s is not a valid %s", number, target)
+		}
+		f = parsed
+	default:
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	result := reflect.New(target).Elem()
+	if result.OverflowFloat(f) {
+		return reflect.Value{}, fmt.Errorf("value %v overflows %s", f, target)
+	}
+	result.SetFloat(f)
+
+	return result, nil
+}
+
+func coerceSlice(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	result := reflect.MakeSlice(target, value.Len(), value.Len())
+	for i := 0; i < value.Len(); i++ {
+		elem, err := coerceValue(target.Elem(), value.Index(i))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+		}
+		result.Index(i).Set(elem)
+	}
+
+	return result, nil
+}
+
+func coerceArray(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	if value.Len() != target.Len() {
+		return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", target.Len(), value.Len())
+	}
+
+	result := reflect.New(target).Elem()
+	for i := 0; i < value.Len(); i++ {
+		elem, err := coerceValue(target.Elem(), value.Index(i))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+		}
+		result.Index(i).Set(elem)
+	}
+
+	return result, nil
+}
+
+func coerceMap(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	if value.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("type mismatch: cannot set %s to %s", value.Type(), target)
+	}
+
+	result := reflect.MakeMapWithSize(target, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		key, err := coerceMapKey(target.Key(), iter.Key())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+		}
+
+		elem, err := coerceValue(target.Elem(), iter.Value())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+		}
+
+		result.SetMapIndex(key, elem)
+	}
+
+	return result, nil
+}
+
+// coerceMapKey converts a map key, parsing string keys from JSON objects into numeric key types.
+func coerceMapKey(target reflect.Type, key reflect.Value) (reflect.Value, error) {
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+
+	if key.Kind() == reflect.String && target.Kind() != reflect.String && target.Kind() != reflect.Interface {
+		return coerceValue(target, reflect.ValueOf(json.Number(key.String())))
+	}
+
+	return coerceValue(target, key)
+}
+
+// coerceStruct builds a struct from a map keyed by config field names. Fields missing from the map keep
+// their zero value.
+func coerceStruct(target reflect.Type, value reflect.Value) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+	fields := StructFields(target)
+
+	iter := value.MapRange()
+	for iter.Next() {
+		name := fmt.Sprint(iter.Key().Interface())
+		index, ok := fields[name]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown field %q for %s", name, target)
+		}
+
+		field, err := coerceValue(target.FieldByIndex(index).Type, iter.Value())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s: %v", name, err)
+		}
+		result.FieldByIndex(index).Set(field)
+	}
+
+	return result, nil
+}
+
+func canBeNil(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+		return true
 	}
-	return nil, fmt.Errorf("type mismatch: cannot set %T to %T", newValue, currentValue)
+	return false
 }
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type normalizeTest struct {
+	name    string
+	current any
+	input   any
+	format  string
+	want    any
+	wantErr bool
+}
+
+func runNormalizeTests(t *testing.T, tests []normalizeTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSettingWithFormat(tt.current, tt.input, tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizing %v into %T = %#v, want error", tt.input, tt.current, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizing %v into %T returned error: %v", tt.input, tt.current, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("normalizing %v into %T = %#v, want %#v", tt.input, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSettingIntegers(t *testing.T) {
+	tests := []normalizeTest{
+		{name: "int from float64", current: int(0), input: float64(42), want: int(42)},
+		{name: "int negative", current: int(0), input: float64(-42), want: int(-42)},
+		{name: "int fractional", current: int(0), input: 1.5, wantErr: true},
+		{name: "int overflow", current: int(0), input: math.Pow(2, 64), wantErr: true},
+
+		{name: "int8 max", current: int8(0), input: float64(math.MaxInt8), want: int8(math.MaxInt8)},
+		{name: "int8 min", current: int8(0), input: float64(math.MinInt8), want: int8(math.MinInt8)},
+		{name: "int8 overflow", current: int8(0), input: float64(math.MaxInt8 + 1), wantErr: true},
+		{name: "int8 underflow", current: int8(0), input: float64(math.MinInt8 - 1), wantErr: true},
+		{name: "int8 fractional", current: int8(0), input: 0.5, wantErr: true},
+
+		{name: "int16 max", current: int16(0), input: float64(math.MaxInt16), want: int16(math.MaxInt16)},
+		{name: "int16 min", current: int16(0), input: float64(math.MinInt16), want: int16(math.MinInt16)},
+		{name: "int16 overflow", current: int16(0), input: float64(math.MaxInt16 + 1), wantErr: true},
+		{name: "int16 underflow", current: int16(0), input: float64(math.MinInt16 - 1), wantErr: true},
+		{name: "int16 fractional", current: int16(0), input: 10.25, wantErr: true},
+
+		{name: "int32 max", current: int32(0), input: float64(math.MaxInt32), want: int32(math.MaxInt32)},
+		{name: "int32 min", current: int32(0), input: float64(math.MinInt32), want: int32(math.MinInt32)},
+		{name: "int32 overflow", current: int32(0), input: float64(math.MaxInt32 + 1), wantErr: true},
+		{name: "int32 underflow", current: int32(0), input: float64(math.MinInt32 - 1), wantErr: true},
+		{name: "int32 fractional", current: int32(0), input: -3.75, wantErr: true},
+
+		{name: "int64 from float64", current: int64(0), input: float64(1 << 53), want: int64(1 << 53)},
+		{name: "int64 from json.Number", current: int64(0), input: json.Number("9223372036854775807"), want: int64(math.MaxInt64)},
+		{name: "int64 json.Number overflow", current: int64(0), input: json.Number("9223372036854775808"), wantErr: true},
+		{name: "int64 float overflow", current: int64(0), input: math.Pow(2, 63), wantErr: true},
+		{name: "int64 float underflow", current: int64(0), input: -math.Pow(2, 64), wantErr: true},
+		{name: "int64 fractional", current: int64(0), input: 2.5, wantErr: true},
+		{name: "int64 fractional json.Number", current: int64(0), input: json.Number("2.5"), wantErr: true},
+		{name: "int64 from uint64 overflow", current: int64(0), input: uint64(math.MaxUint64), wantErr: true},
+
+		{name: "uint from float64", current: uint(0), input: float64(42), want: uint(42)},
+		{name: "uint negative", current: uint(0), input: float64(-1), wantErr: true},
+		{name: "uint fractional", current: uint(0), input: 4.2, wantErr: true},
+		{name: "uint overflow", current: uint(0), input: math.Pow(2, 64), wantErr: true},
+
+		{name: "uint8 max", current: uint8(0), input: float64(math.MaxUint8), want: uint8(math.MaxUint8)},
+		{name: "uint8 overflow", current: uint8(0), input: float64(math.MaxUint8 + 1), wantErr: true},
+		{name: "uint8 negative", current: uint8(0), input: float64(-1), wantErr: true},
+		{name: "uint8 fractional", current: uint8(0), input: 1.5, wantErr: true},
+
+		{name: "uint16 max", current: uint16(0), input: float64(math.MaxUint16), want: uint16(math.MaxUint16)},
+		{name: "uint16 overflow", current: uint16(0), input: float64(math.MaxUint16 + 1), wantErr: true},
+		{name: "uint16 negative", current: uint16(0), input: float64(-1), wantErr: true},
+		{name: "uint16 fractional", current: uint16(0), input: 80.5, wantErr: true},
+
+		{name: "uint32 max", current: uint32(0), input: float64(math.MaxUint32), want: uint32(math.MaxUint32)},
+		{name: "uint32 overflow", current: uint32(0), input: float64(math.MaxUint32 + 1), wantErr: true},
+		{name: "uint32 negative", current: uint32(0), input: float64(-1), wantErr: true},
+		{name: "uint32 fractional", current: uint32(0), input: 0.1, wantErr: true},
+
+		{name: "uint64 from json.Number", current: uint64(0), input: json.Number("18446744073709551615"), want: uint64(math.MaxUint64)},
+		{name: "uint64 json.Number overflow", current: uint64(0), input: json.Number("18446744073709551616"), wantErr: true},
+		{name: "uint64 float overflow", current: uint64(0), input: math.Pow(2, 64), wantErr: true},
+		{name: "uint64 negative", current: uint64(0), input: float64(-1), wantErr: true},
+		{name: "uint64 negative int", current: uint64(0), input: int(-1), wantErr: true},
+		{name: "uint64 fractional", current: uint64(0), input: 7.5, wantErr: true},
+
+		{name: "int from string", current: int(0), input: "42", wantErr: true},
+		{name: "int from nil", current: int(0), input: nil, wantErr: true},
+	}
+
+	runNormalizeTests(t, tests)
+}
+
+func TestNormalizeSettingFloats(t *testing.T) {
+	tests := []normalizeTest{
+		{name: "float64 from float64", current: float64(0), input: 1.5, want: 1.5},
+		{name: "float64 from int", current: float64(0), input: 3, want: float64(3)},
+		{name: "float32 from float64", current: float32(0), input: 0.25, want: float32(0.25)},
+		{name: "float32 overflow", current: float32(0), input: math.MaxFloat64, wantErr: true},
+		{name: "float64 from json.Number", current: float64(0), input: json.Number("2.5"), want: 2.5},
+		{name: "float64 from string", current: float64(0), input: "2.5", wantErr: true},
+	}
+
+	runNormalizeTests(t, tests)
+}
+
+func TestNormalizeSettingDurations(t *testing.T) {
+	tests := []normalizeTest{
+		{name: "duration from string", current: time.Duration(0), input: "1h30m", want: 90 * time.Minute},
+		{name: "duration invalid string", current: time.Duration(0), input: "soon", wantErr: true},
+		{name: "duration from float seconds", current: time.Duration(0), input: 1.5, want: 1500 * time.Millisecond},
+		{name: "duration from int seconds", current: time.Duration(0), input: 90, want: 90 * time.Second},
+		{name: "duration from int64 seconds", current: time.Duration(0), input: int64(-5), want: -5 * time.Second},
+		{name: "duration from uint64 seconds", current: time.Duration(0), input: uint64(60), want: time.Minute},
+		{name: "duration from json.Number", current: time.Duration(0), input: json.Number("30"), want: 30 * time.Second},
+		{name: "duration from fractional json.Number", current: time.Duration(0), input: json.Number("0.25"), want: 250 * time.Millisecond},
+		{name: "duration invalid json.Number", current: time.Duration(0), input: json.Number("1e"), wantErr: true},
+		{name: "duration float overflow", current: time.Duration(0), input: 3.6e12, wantErr: true},
+		{name: "duration float underflow", current: time.Duration(0), input: -3.6e12, wantErr: true},
+		{name: "duration float NaN", current: time.Duration(0), input: math.NaN(), wantErr: true},
+		{name: "duration int overflow", current: time.Duration(0), input: int64(math.MaxInt64/int64(time.Second) + 1), wantErr: true},
+		{name: "duration int underflow", current: time.Duration(0), input: int64(math.MinInt64/int64(time.Second) - 1), wantErr: true},
+		{name: "duration uint overflow", current: time.Duration(0), input: uint64(math.MaxUint64), wantErr: true},
+		{name: "duration json.Number overflow", current: time.Duration(0), input: json.Number("3600000000000"), wantErr: true},
+		{name: "duration from bool", current: time.Duration(0), input: true, wantErr: true},
+	}
+
+	runNormalizeTests(t, tests)
+}
+
+type normalizeBackend struct {
+	Name    string        `config:"name"`
+	Port    uint16        `config:"port"`
+	Timeout time.Duration `config:"timeout"`
+	Tags    []string      `config:"tags"`
+}
+
+// Listener is exported so it is promoted like the embedded structs of the portal config
+type Listener struct {
+	Name string `config:"name"`
+	Port uint16 `config:"port"`
+}
+
+type normalizeEmbedded struct {
+	Listener
+	Name   string `config:"name"`
+	Weight int    `config:"weight"`
+}
+
+func TestNormalizeSettingNested(t *testing.T) {
+	tests := []normalizeTest{
+		{
+			name:    "int slice from JSON array",
+			current: []int{},
+			input:   []any{float64(1), float64(2), float64(3)},
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "uint16 slice element overflow",
+			current: []uint16{},
+			input:   []any{float64(1), float64(math.MaxUint16 + 1)},
+			wantErr: true,
+		},
+		{
+			name:    "int32 slice element fractional",
+			current: []int32{},
+			input:   []any{float64(1), 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "nested int slices",
+			current: [][]int64{},
+			input:   []any{[]any{float64(1)}, []any{float64(2), float64(3)}},
+			want:    [][]int64{{1}, {2, 3}},
+		},
+		{
+			name:    "fixed array",
+			current: [2]uint8{},
+			input:   []any{float64(1), float64(2)},
+			want:    [2]uint8{1, 2},
+		},
+		{
+			name:    "fixed array wrong length",
+			current: [2]uint8{},
+			input:   []any{float64(1)},
+			wantErr: true,
+		},
+		{
+			name:    "map of int64 from JSON object",
+			current: map[string]int64{},
+			input:   map[string]any{"a": float64(1), "b": float64(-2)},
+			want:    map[string]int64{"a": 1, "b": -2},
+		},
+		{
+			name:    "map value fractional",
+			current: map[string]int{},
+			input:   map[string]any{"a": 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "map with numeric keys",
+			current: map[uint32]string{},
+			input:   map[string]any{"7": "seven"},
+			want:    map[uint32]string{7: "seven"},
+		},
+		{
+			name:    "map with invalid numeric key",
+			current: map[uint32]string{},
+			input:   map[string]any{"-7": "minus seven"},
+			wantErr: true,
+		},
+		{
+			name:    "map of int slices",
+			current: map[string][]int8{},
+			input:   map[string]any{"a": []any{float64(1), float64(-1)}},
+			want:    map[string][]int8{"a": {1, -1}},
+		},
+		{
+			name:    "struct from JSON object",
+			current: normalizeBackend{},
+			input:   map[string]any{"name": "main", "port": float64(8080), "timeout": "5s", "tags": []any{"a"}},
+			want:    normalizeBackend{Name: "main", Port: 8080, Timeout: 5 * time.Second, Tags: []string{"a"}},
+		},
+		{
+			name:    "struct field overflow",
+			current: normalizeBackend{},
+			input:   map[string]any{"port": float64(70000)},
+			wantErr: true,
+		},
+		{
+			name:    "struct unknown field",
+			current: normalizeBackend{},
+			input:   map[string]any{"host": "example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "slice of structs",
+			current: []normalizeBackend{},
+			input:   []any{map[string]any{"name": "a", "port": float64(1)}},
+			want:    []normalizeBackend{{Name: "a", Port: 1}},
+		},
+		{
+			name:    "embedded struct fields",
+			current: normalizeEmbedded{},
+			input:   map[string]any{"name": "outer", "port": float64(80), "weight": float64(2)},
+			want:    normalizeEmbedded{Listener: Listener{Port: 80}, Name: "outer", Weight: 2},
+		},
+		{
+			name:    "pointer to struct",
+			current: &normalizeBackend{},
+			input:   map[string]any{"port": float64(443)},
+			want:    &normalizeBackend{Port: 443},
+		},
+	}
+
+	runNormalizeTests(t, tests)
+}
+
+func TestNormalizeSettingWithFormat(t *testing.T) {
+	tests := []normalizeTest{
+		{name: "bytes from units", current: uint64(0), input: "10KiB", format: FormatBytes, want: uint64(10 << 10)},
+		{name: "bytes from number", current: int64(0), input: float64(512), format: FormatBytes, want: int64(512)},
+		{name: "bytes invalid unit", current: uint64(0), input: "10XB", format: FormatBytes, wantErr: true},
+		{name: "duration into int64", current: int64(0), input: "1m", format: FormatDuration, want: int64(time.Minute)},
+		{name: "duration into time.Duration", current: time.Duration(0), input: "1h30m", format: FormatDuration, want: 90 * time.Minute},
+		{name: "duration overflow int32", current: int32(0), input: "1h", format: FormatDuration, wantErr: true},
+	}
+
+	runNormalizeTests(t, tests)
+}
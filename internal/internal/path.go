@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/stoewer/go-strcase"
+	"reflect"
+	"strconv"
+)
+
+// FieldName returns the name a struct field has in the config, taken from its config tag or derived
+// from the Go name in snake_case.
+func FieldName(field reflect.StructField) string {
+	if configTag := field.Tag.Get("config"); configTag != "" {
+		return configTag
+	}
+	return strcase.SnakeCase(field.Name)
+}
+
+// LookupPath follows the path segments into a value, treating them as list indexes, map keys or struct
+// field names depending on what the value holds at each step.
+func LookupPath(value any, path []string) (any, bool) {
+	current := reflect.ValueOf(value)
+	for _, part := range path {
+		next, ok := childValue(current, part)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+
+	if !current.IsValid() {
+		return nil, false
+	}
+	return current.Interface(), true
+}
+
+// SetPath returns a copy of value with the element at path replaced by the result of update, which is
+// passed the current element. Only the containers along the path are copied, the original is not modified.
+func SetPath(value any, path []string, update func(current any) (any, error)) (any, error) {
+	updated, err := setPath(reflect.ValueOf(value), path, update)
+	if err != nil {
+		return nil, err
+	}
+	return updated.Interface(), nil
+}
+
+func setPath(v reflect.Value, path []string, update func(current any) (any, error)) (reflect.Value, error) {
+	if len(path) == 0 {
+		var current any
+		if v.IsValid() {
+			current = v.Interface()
+		}
+
+		newValue, err := update(current)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		result := reflect.ValueOf(newValue)
+		if v.IsValid() && result.IsValid() && result.Type() != v.Type() {
+			if !result.Type().ConvertibleTo(v.Type()) {
+				return reflect.Value{}, fmt.Errorf("cannot use %s as %s", result.Type(), v.Type())
+			}
+			result = result.Convert(v.Type())
+		}
+		return result, nil
+	}
+
+	part := path[0]
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("path not found: %s", part)
+		}
+		elem, err := setPath(v.Elem(), path, update)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(v.Type().Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("path not found: %s", part)
+		}
+		return setPath(v.Elem(), path, update)
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid array index: %v", err)
+		}
+		if index < 0 || index >= v.Len() {
+			return reflect.Value{}, fmt.Errorf("array index out of bounds: %d", index)
+		}
+
+		elem, err := setPath(v.Index(index), path[1:], update)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		var copied reflect.Value
+		if v.Kind() == reflect.Slice {
+			copied = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		} else {
+			copied = reflect.New(v.Type()).Elem()
+		}
+		reflect.Copy(copied, v)
+		copied.Index(index).Set(assignable(elem, v.Type().Elem()))
+		return copied, nil
+	case reflect.Map:
+		key, err := MapKey(part, v.Type().Key())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		current := v.MapIndex(key)
+		if !current.IsValid() {
+			return reflect.Value{}, fmt.Errorf("path not found: %s", part)
+		}
+
+		elem, err := setPath(current, path[1:], update)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), iter.Value())
+		}
+		copied.SetMapIndex(key, assignable(elem, v.Type().Elem()))
+		return copied, nil
+	case reflect.Struct:
+		index, ok := structField(v.Type(), part)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("path not found: %s", part)
+		}
+
+		elem, err := setPath(v.FieldByIndex(index), path[1:], update)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		copied.FieldByIndex(index).Set(assignable(elem, v.Type().FieldByIndex(index).Type))
+		return copied, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("path not found: %s", part)
+}
+
+func childValue(v reflect.Value, part string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(part)
+		if err != nil || index < 0 || index >= v.Len() {
+			return reflect.Value{}, false
+		}
+		return v.Index(index), true
+	case reflect.Map:
+		key, err := MapKey(part, v.Type().Key())
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		elem := v.MapIndex(key)
+		return elem, elem.IsValid()
+	case reflect.Struct:
+		index, ok := structField(v.Type(), part)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		return v.FieldByIndex(index), true
+	}
+
+	return reflect.Value{}, false
+}
+
+// StructFields maps the config names of a struct's exported fields to their index paths. Fields of embedded
+// structs are promoted, the same way the settings schema flattens them, and shadowed by direct fields.
+func StructFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		fields[FieldName(field)] = field.Index
+	}
+
+	for _, field := range embedded {
+		for name, index := range StructFields(field.Type) {
+			if _, shadowed := fields[name]; !shadowed {
+				fields[name] = append(append([]int{}, field.Index...), index...)
+			}
+		}
+	}
+
+	return fields
+}
+
+func structField(t reflect.Type, name string) ([]int, bool) {
+	index, ok := StructFields(t)[name]
+	return index, ok
+}
+
+// MapKey converts a path segment to the key type of a map.
+func MapKey(part string, keyType reflect.Type) (reflect.Value, error) {
+	return coerceMapKey(keyType, reflect.ValueOf(part))
+}
+
+// assignable returns a value that can be stored in a slot of type t, using the zero value for nil.
+func assignable(v reflect.Value, t reflect.Type) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Zero(t)
+	}
+	if v.Type() != t && v.Type().ConvertibleTo(t) && t.Kind() != reflect.Interface {
+		return v.Convert(t)
+	}
+	return v
+}
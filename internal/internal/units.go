@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Schema formats for settings that accept human-friendly units.
+const (
+	FormatBytes    = "bytes"
+	FormatDuration = "duration"
+)
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1e15,
+	"pib": 1 << 50,
+}
+
+var binarySizeUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+}
+
+// ParseSize parses a byte size such as "500MB", "10GiB" or "1.5 TiB". Decimal units (KB, MB, ...)
+// are powers of 1000, binary units (KiB, MiB, ...) and bare letters (K, M, ...) are powers of 1024.
+func ParseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if split == -1 {
+		split = len(s)
+	}
+
+	number, unit := s[:split], strings.ToLower(strings.TrimSpace(s[split:]))
+	if number == "" {
+		return 0, fmt.Errorf("invalid size %q: missing number", s)
+	}
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %v", s, err)
+	}
+
+	size := value * multiplier
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	if size != math.Trunc(size) {
+		return 0, fmt.Errorf("invalid size %q: not a whole number of bytes", s)
+	}
+
+	return uint64(size), nil
+}
+
+// FormatSize renders a byte count using the largest binary unit, e.g. 1610612736 becomes "1.5GiB".
+func FormatSize(size uint64) string {
+	for _, unit := range binarySizeUnits {
+		if size >= unit.size {
+			value := float64(size) / float64(unit.size)
+			return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatUint(size, 10) + "B"
+}
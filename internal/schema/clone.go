@@ -0,0 +1,40 @@
+package schema
+
+import orderedmap "github.com/wk8/go-ordered-map/v2"
+
+// Clone returns a copy of the schema where the property, item, additional property and definition
+// sub-schemas are copied as well, so they can be modified without affecting the original. Other
+// sub-schemas are shared.
+func (s *Schema) Clone() *Schema {
+	if s == nil {
+		return nil
+	}
+
+	clone := *s
+
+	if s.Properties != nil {
+		clone.Properties = orderedmap.New[string, *Schema]()
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			clone.Properties.Set(pair.Key, pair.Value.Clone())
+		}
+	}
+
+	if s.Definitions != nil {
+		clone.Definitions = make(Definitions, len(s.Definitions))
+		for name, definition := range s.Definitions {
+			clone.Definitions[name] = definition.Clone()
+		}
+	}
+
+	clone.Items = s.Items.Clone()
+	clone.AdditionalProperties = s.AdditionalProperties.Clone()
+
+	if s.Extras != nil {
+		clone.Extras = make(map[string]any, len(s.Extras))
+		for key, value := range s.Extras {
+			clone.Extras[key] = value
+		}
+	}
+
+	return &clone
+}
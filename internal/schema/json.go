@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// schemaKeywords holds the JSON names of the keywords mapped to struct fields, so Extras can never
+// shadow or duplicate them.
+var schemaKeywords = func() map[string]struct{} {
+	keywords := make(map[string]struct{})
+
+	t := reflect.TypeOf(Schema{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keywords[name] = struct{}{}
+		}
+	}
+
+	return keywords
+}()
+
+// schemaAlias has the same fields as Schema but none of its methods, so it can be encoded and decoded
+// without recursing into MarshalJSON and UnmarshalJSON.
+type schemaAlias Schema
+
+// MarshalJSON encodes boolean schemas as true or false, and inlines Extras as top-level keywords
+// alongside the standard ones. It has a value receiver so schemas held by value encode the same way.
+func (t Schema) MarshalJSON() ([]byte, error) {
+	if t.boolean != nil {
+		if *t.boolean {
+			return []byte("true"), nil
+		}
+		return []byte("false"), nil
+	}
+
+	data, err := json.Marshal((*schemaAlias)(&t))
+	if err != nil {
+		return nil, err
+	}
+
+	extras := make(map[string]any, len(t.Extras))
+	for key, value := range t.Extras {
+		if _, reserved := schemaKeywords[key]; !reserved {
+			extras[key] = value
+		}
+	}
+
+	if len(extras) == 0 {
+		return data, nil
+	}
+
+	extraData, err := json.Marshal(extras)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(data, []byte("{}")) {
+		return extraData, nil
+	}
+
+	// Splice the two objects together: {"type":...} + {"x-foo":...}
+	merged := make([]byte, 0, len(data)+len(extraData))
+	merged = append(merged, data[:len(data)-1]...)
+	merged = append(merged, ',')
+	merged = append(merged, extraData[1:]...)
+
+	return merged, nil
+}
+
+// UnmarshalJSON decodes boolean schemas as well as objects. Keywords without a matching field are
+// kept in Extras.
+func (t *Schema) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(trimmed, []byte("true")):
+		value := true
+		*t = Schema{boolean: &value}
+		return nil
+	case bytes.Equal(trimmed, []byte("false")):
+		value := false
+		*t = Schema{boolean: &value}
+		return nil
+	}
+
+	var decoded schemaAlias
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for key, value := range raw {
+		if _, known := schemaKeywords[key]; known {
+			continue
+		}
+
+		var extra any
+		if err := json.Unmarshal(value, &extra); err != nil {
+			return err
+		}
+
+		if decoded.Extras == nil {
+			decoded.Extras = make(map[string]any)
+		}
+		decoded.Extras[key] = extra
+	}
+
+	*t = Schema(decoded)
+	return nil
+}
+
+// Boolean reports whether the schema is a boolean schema, and if so its value.
+func (t *Schema) Boolean() (value bool, ok bool) {
+	if t.boolean == nil {
+		return false, false
+	}
+	return *t.boolean, true
+}
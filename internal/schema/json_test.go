@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaJSONGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden files found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			golden, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var schema Schema
+			if err := json.Unmarshal(golden, &schema); err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+
+			encoded, err := json.MarshalIndent(&schema, "", "  ")
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+
+			if want := bytes.TrimSpace(golden); !bytes.Equal(encoded, want) {
+				t.Fatalf("round trip mismatch\ngot:\n%s\nwant:\n%s", encoded, want)
+			}
+		})
+	}
+}
+
+func TestSchemaJSONBoolean(t *testing.T) {
+	for _, data := range []string{"true", "false"} {
+		var schema Schema
+		if err := json.Unmarshal([]byte(data), &schema); err != nil {
+			t.Fatalf("decoding %s: %v", data, err)
+		}
+
+		value, ok := schema.Boolean()
+		if !ok || value != (data == "true") {
+			t.Fatalf("decoding %s: Boolean() = %v, %v", data, value, ok)
+		}
+
+		encoded, err := json.Marshal(&schema)
+		if err != nil {
+			t.Fatalf("encoding %s: %v", data, err)
+		}
+		if string(encoded) != data {
+			t.Fatalf("encoding %s = %s", data, encoded)
+		}
+	}
+}
+
+func TestSchemaJSONExtras(t *testing.T) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(`{"type":"string","x-order":1,"x-tags":["a"]}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.Type != "string" {
+		t.Fatalf("Type = %q, want string", schema.Type)
+	}
+	if len(schema.Extras) != 2 || schema.Extras["x-order"] != float64(1) {
+		t.Fatalf("Extras = %#v", schema.Extras)
+	}
+	if _, ok := schema.Extras["type"]; ok {
+		t.Fatal("standard keyword decoded into Extras")
+	}
+}
+
+func TestSchemaJSONReservedExtras(t *testing.T) {
+	schema := &Schema{Type: "string", Extras: map[string]any{"type": "integer", "x-order": 1}}
+
+	encoded, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"type":"string","x-order":1}`; string(encoded) != want {
+		t.Fatalf("encoding = %s, want %s", encoded, want)
+	}
+}
+
+func TestSchemaJSONByValue(t *testing.T) {
+	schemas := map[string]Schema{
+		"extra":   {Type: "string", Extras: map[string]any{"x-order": 1}},
+		"boolean": *FalseSchema,
+	}
+
+	encoded, err := json.Marshal(schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"boolean":false,"extra":{"type":"string","x-order":1}}`; string(encoded) != want {
+		t.Fatalf("encoding = %s, want %s", encoded, want)
+	}
+
+	var decoded map[string]Schema
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	boolean := decoded["boolean"]
+	if value, ok := boolean.Boolean(); !ok || value {
+		t.Fatalf("decoded boolean schema = %v, %v", value, ok)
+	}
+}
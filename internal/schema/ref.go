@@ -0,0 +1,84 @@
+package schema
+
+import "strings"
+
+const definitionsRefPrefix = "#/$defs/"
+
+// DefinitionRef returns the $ref pointing at the named entry of the root schema's $defs.
+func DefinitionRef(name string) string {
+	return definitionsRefPrefix + name
+}
+
+// Resolve follows a $ref into the $defs of the root schema. It returns s itself if it is not a
+// reference, and nil if the reference does not resolve.
+func (s *Schema) Resolve(root *Schema) *Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+
+	name, ok := strings.CutPrefix(s.Ref, definitionsRefPrefix)
+	if !ok || root == nil {
+		return nil
+	}
+
+	return root.Definitions[name]
+}
+
+// Inline returns a copy of the schema where references into the $defs of the root schema are replaced by
+// the definitions themselves, so the result can be used without the root. Annotations set next to a
+// reference take precedence over those of the definition.
+func (s *Schema) Inline(root *Schema) *Schema {
+	clone := s.Clone()
+	clone.inline(root, make(map[string]bool))
+	return clone
+}
+
+func (s *Schema) inline(root *Schema, seen map[string]bool) {
+	if s == nil {
+		return
+	}
+
+	if name, ok := strings.CutPrefix(s.Ref, definitionsRefPrefix); ok && !seen[name] {
+		if definition := s.Resolve(root); definition != nil {
+			merged := definition.Clone()
+			merged.annotateFrom(s)
+			*s = *merged
+
+			seen[name] = true
+			defer delete(seen, name)
+		}
+	}
+
+	if s.Properties != nil {
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			pair.Value.inline(root, seen)
+		}
+	}
+	s.Items.inline(root, seen)
+	s.AdditionalProperties.inline(root, seen)
+}
+
+func (s *Schema) annotateFrom(ref *Schema) {
+	if ref.Title != "" {
+		s.Title = ref.Title
+	}
+	if ref.Description != "" {
+		s.Description = ref.Description
+	}
+	if ref.Default != nil {
+		s.Default = ref.Default
+	}
+	if ref.Examples != nil {
+		s.Examples = ref.Examples
+	}
+	s.Deprecated = s.Deprecated || ref.Deprecated
+	s.ReadOnly = s.ReadOnly || ref.ReadOnly
+	s.WriteOnly = s.WriteOnly || ref.WriteOnly
+
+	for key, value := range ref.Extras {
+		if s.Extras == nil {
+			s.Extras = make(map[string]any)
+		}
+		s.Extras[key] = value
+	}
+}
@@ -0,0 +1,248 @@
+package schema
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"gopkg.in/yaml.v3"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// unitFormats are formats whose values may also be written as strings with units, such as "10MB" or "1h".
+var unitFormats = map[string]bool{
+	"bytes":    true,
+	"duration": true,
+}
+
+// Validate checks a value against the type, enum, range, length and pattern keywords of the schema,
+// descending into properties, items and additional properties. Struct values are checked field by field
+// against the properties named after their config fields. Composition keywords and references are not
+// evaluated, so references should be inlined first.
+func (s *Schema) Validate(value any) error {
+	return s.validate("value", value)
+}
+
+func (s *Schema) validate(path string, value any) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.boolean != nil {
+		if !*s.boolean {
+			return fmt.Errorf("%s: no value is allowed", path)
+		}
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, v.Interface()) {
+		return fmt.Errorf("%s: must be one of %v", path, s.Enum)
+	}
+
+	switch s.Type {
+	case "string":
+		if v.Kind() == reflect.Struct {
+			// Typed values encoded as strings, such as time.Time
+			return nil
+		}
+		if v.Kind() != reflect.String {
+			return typeMismatch(path, s.Type, v)
+		}
+		return s.validateString(path, v.String())
+	case "integer", "number":
+		if v.Kind() == reflect.String && unitFormats[s.Format] {
+			// Parsed into a number when the setting is normalized
+			return nil
+		}
+		n, ok := numberValue(v)
+		if !ok || (s.Type == "integer" && n != math.Trunc(n)) {
+			return typeMismatch(path, s.Type, v)
+		}
+		return s.validateNumber(path, n)
+	case "boolean":
+		if v.Kind() != reflect.Bool {
+			return typeMismatch(path, s.Type, v)
+		}
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return typeMismatch(path, s.Type, v)
+		}
+		return s.validateArray(path, v)
+	case "object":
+		switch v.Kind() {
+		case reflect.Map:
+			return s.validateObject(path, v)
+		case reflect.Struct:
+			return s.validateStruct(path, v)
+		}
+		return typeMismatch(path, s.Type, v)
+	}
+
+	return nil
+}
+
+func (s *Schema) validateString(path string, value string) error {
+	length := uint64(utf8.RuneCountInString(value))
+	if s.MinLength != nil && length < *s.MinLength {
+		return fmt.Errorf("%s: must be at least %d characters", path, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Errorf("%s: must be at most %d characters", path, *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %v", path, s.Pattern, err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("%s: must match pattern %s", path, s.Pattern)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateNumber(path string, value float64) error {
+	if s.Minimum != "" {
+		if minimum, err := s.Minimum.Float64(); err == nil && value < minimum {
+			return fmt.Errorf("%s: must be at least %s", path, s.Minimum)
+		}
+	}
+	if s.Maximum != "" {
+		if maximum, err := s.Maximum.Float64(); err == nil && value > maximum {
+			return fmt.Errorf("%s: must be at most %s", path, s.Maximum)
+		}
+	}
+	if s.ExclusiveMinimum != "" {
+		if minimum, err := s.ExclusiveMinimum.Float64(); err == nil && value <= minimum {
+			return fmt.Errorf("%s: must be greater than %s", path, s.ExclusiveMinimum)
+		}
+	}
+	if s.ExclusiveMaximum != "" {
+		if maximum, err := s.ExclusiveMaximum.Float64(); err == nil && value >= maximum {
+			return fmt.Errorf("%s: must be less than %s", path, s.ExclusiveMaximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(path string, v reflect.Value) error {
+	length := uint64(v.Len())
+	if s.MinItems != nil && length < *s.MinItems {
+		return fmt.Errorf("%s: must have at least %d items", path, *s.MinItems)
+	}
+	if s.MaxItems != nil && length > *s.MaxItems {
+		return fmt.Errorf("%s: must have at most %d items", path, *s.MaxItems)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := s.Items.validate(path+"."+strconv.Itoa(i), v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, v reflect.Value) error {
+	iter := v.MapRange()
+	for iter.Next() {
+		name := fmt.Sprint(iter.Key().Interface())
+		if err := s.PropertyNames.validate(path+" key "+strconv.Quote(name), name); err != nil {
+			return err
+		}
+
+		propertySchema := s.AdditionalProperties
+		if s.Properties != nil {
+			if property, ok := s.Properties.Get(name); ok {
+				propertySchema = property
+			}
+		}
+
+		if err := propertySchema.validate(path+"."+name, iter.Value().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+
+// validateStruct checks the exported fields of a typed value, including those of embedded structs, against
+// the properties named after them. Types that marshal themselves are described by their marshaled form
+// rather than their fields, so they are not checked.
+func (s *Schema) validateStruct(path string, v reflect.Value) error {
+	if s.Properties == nil || v.Type().Implements(yamlMarshalerType) || reflect.PointerTo(v.Type()).Implements(yamlMarshalerType) {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := s.validateStruct(path, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := internal.FieldName(field)
+		property, ok := s.Properties.Get(name)
+		if !ok {
+			continue
+		}
+
+		if err := property.validate(path+"."+name, v.Field(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func typeMismatch(path string, expected string, v reflect.Value) error {
+	return fmt.Errorf("%s: expected %s, got %s", path, expected, v.Kind())
+}
+
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		// json.Number
+		if v.Type().Name() == "Number" {
+			n, err := strconv.ParseFloat(v.String(), 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+func enumContains(enum []any, value any) bool {
+	for _, option := range enum {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,11 @@
 package service
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/samber/lo"
 	"github.com/stoewer/go-strcase"
@@ -9,19 +14,68 @@ import (
 	"go.lumeweb.com/portal-plugin-admin/internal/internal"
 	"go.lumeweb.com/portal-plugin-admin/internal/schema"
 	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"path"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const ADMIN_SETTINGS_SERVICE = "admin_settings"
 
-var configSchema *schema.Schema
+var (
+	ErrSettingNotFound    = errors.New("setting not found")
+	ErrSettingNotEditable = errors.New("setting is not editable")
+	ErrSettingNoDefault   = errors.New("setting has no default value")
+	ErrVersionMismatch    = errors.New("setting has been modified")
+)
+
+// schemaShapeCheckInterval limits how often reads compare the config shape against the cached schema.
+const schemaShapeCheckInterval = time.Second
+
+// settingsSchema is everything derived from walking the config structs. It is replaced as a whole
+// whenever the config shape changes and never modified after being built.
+type settingsSchema struct {
+	schema    *schema.Schema
+	sensitive map[string]bool
+	restart   map[string]bool
+	defaults  map[string]any
+	shape     string
+}
 
 type AdminSettingsService struct {
 	ctx core.Context
+	db  *gorm.DB
+
+	schemaState     atomic.Pointer[settingsSchema]
+	schemaMu        sync.Mutex
+	schemaCheckedAt atomic.Int64
+
+	pendingMu       sync.RWMutex
+	pendingRestarts map[string]*pendingRestart
+
+	// updateMu serializes updates so version checks and writes happen atomically
+	updateMu sync.Mutex
+
+	eventsMu     sync.Mutex
+	subscribers  map[chan *messages.SettingsEvent]struct{}
+	seenVersions map[string]string
+	watchStop    chan struct{}
+
+	// protectedPrefixes caches ListProtectedPrefixes until SetProtectedPrefixes changes them
+	protectedMu       sync.Mutex
+	protectedPrefixes []string
+
+	layersMu     sync.RWMutex
+	runtimeEdits map[string]any
+	layers       *configLayers
+	layersStamp  string
 }
 
 func (a *AdminSettingsService) ID() string {
@@ -29,27 +83,22 @@ func (a *AdminSettingsService) ID() string {
 }
 
 func NewAdminSettingsService() (core.Service, []core.ContextBuilderOption, error) {
-	adminSettingsService := &AdminSettingsService{}
+	adminSettingsService := &AdminSettingsService{
+		pendingRestarts: make(map[string]*pendingRestart),
+		subscribers:     make(map[chan *messages.SettingsEvent]struct{}),
+		runtimeEdits:    make(map[string]any),
+	}
 
 	opts := core.ContextOptions(
 		core.ContextWithStartupFunc(func(ctx core.Context) error {
 			adminSettingsService.ctx = ctx
-
-			_schema := &schema.Schema{
-				Version:    "https://json-schema.org/draft/2020-12/schema",
-				ID:         "https://go.lumeweb.com/portal/config/config",
-				Type:       "object",
-				Properties: orderedmap.New[string, *schema.Schema](),
-			}
-
-			builder := &schemaBuilder{schema: _schema, ctx: ctx}
-			err := ctx.Config().FieldProcessor(ctx.Config().Config(), "", builder.buildSchema)
-			if err != nil {
+			adminSettingsService.db = ctx.DB()
+			if err := adminSettingsService.loadVersionKey(); err != nil {
 				return err
 			}
+			core.GetService[core.CronService](ctx, core.CRON_SERVICE).RegisterEntity(adminSettingsService)
 
-			configSchema = _schema
-			return nil
+			return adminSettingsService.RebuildSchema()
 		}),
 	)
 
@@ -57,16 +106,152 @@ func NewAdminSettingsService() (core.Service, []core.ContextBuilderOption, error
 }
 
 func (a *AdminSettingsService) GetSchema() *schema.Schema {
-	return configSchema
+	return a.currentSchema().schema
+}
+
+// RebuildSchema walks the config again and replaces the cached schema.
+func (a *AdminSettingsService) RebuildSchema() error {
+	a.schemaMu.Lock()
+	defer a.schemaMu.Unlock()
+
+	return a.rebuildSchema(a.configShape())
+}
+
+func (a *AdminSettingsService) rebuildSchema(shape string) error {
+	state, err := buildSettingsSchema(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	state.shape = shape
+	a.schemaState.Store(state)
+	a.schemaCheckedAt.Store(time.Now().UnixNano())
+
+	return nil
+}
+
+// currentSchema returns the cached schema, rebuilding it first if the config shape has changed since it
+// was built, for example because entries were added to a list or a plugin registered its config late.
+func (a *AdminSettingsService) currentSchema() *settingsSchema {
+	state := a.schemaState.Load()
+
+	checkedAt := a.schemaCheckedAt.Load()
+	if state != nil && time.Since(time.Unix(0, checkedAt)) < schemaShapeCheckInterval {
+		return state
+	}
+
+	a.schemaMu.Lock()
+	defer a.schemaMu.Unlock()
+
+	if state = a.schemaState.Load(); state != nil && a.schemaCheckedAt.Load() != checkedAt {
+		// Another caller checked while we waited for the lock
+		return state
+	}
+
+	shape := a.configShape()
+	if state != nil && state.shape == shape {
+		a.schemaCheckedAt.Store(time.Now().UnixNano())
+		return state
+	}
+
+	if err := a.rebuildSchema(shape); err != nil {
+		a.ctx.Logger().Error("failed to rebuild settings schema", zap.Error(err))
+		if state != nil {
+			return state
+		}
+		return &settingsSchema{schema: &schema.Schema{Type: "object"}}
+	}
+
+	return a.schemaState.Load()
+}
+
+// configShape fingerprints the config keys along with the sizes of list and map values, which is what the
+// schema's structure depends on.
+func (a *AdminSettingsService) configShape() string {
+	all := a.ctx.Config().All()
+
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		if v := reflect.ValueOf(all[key]); v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+			hash.Write([]byte("#" + strconv.Itoa(v.Len())))
+		}
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func buildSettingsSchema(ctx core.Context) (*settingsSchema, error) {
+	_schema := &schema.Schema{
+		Version:    "https://json-schema.org/draft/2020-12/schema",
+		ID:         "https://go.lumeweb.com/portal/config/config",
+		Type:       "object",
+		Properties: orderedmap.New[string, *schema.Schema](),
+	}
+
+	builder := &schemaBuilder{
+		schema:    _schema,
+		ctx:       ctx,
+		sensitive: make(map[string]bool),
+		restart:   make(map[string]bool),
+		defaults:  make(map[string]any),
+		visiting:  make(map[reflect.Type]bool),
+
+		structUses: make(map[reflect.Type][]*schema.Schema),
+	}
+	builder.collectDefaults(reflect.ValueOf(ctx.Config().Config()), "")
+	err := ctx.Config().FieldProcessor(ctx.Config().Config(), "", builder.buildSchema)
+	if err != nil {
+		return nil, err
+	}
+	builder.applyDefaults()
+	builder.hoistDefinitions()
+
+	return &settingsSchema{
+		schema:    _schema,
+		sensitive: builder.sensitive,
+		restart:   builder.restart,
+		defaults:  builder.defaults,
+	}, nil
+}
+
+// GetSchemaPath resolves a dotted path, which may include array indexes and map keys, to the matching
+// sub-schema as seen by the actor. It returns nil if the path does not exist.
+func (a *AdminSettingsService) GetSchemaPath(access *SettingsAccess, path string) *schema.Schema {
+	root := a.GetSchemaFor(access)
+	pathSchema := schemaForKey(root, path)
+	if pathSchema == nil {
+		return nil
+	}
+	return pathSchema.Inline(root)
 }
 
 func (a *AdminSettingsService) GetSettings() []*messages.SettingsItem {
+	layers := a.loadLayers()
+	locks, err := a.activeLocks()
+	if err != nil {
+		a.ctx.Logger().Error("failed to load setting locks", zap.Error(err))
+	}
+
 	settings := lo.MapToSlice(a.ctx.Config().All(), func(k string, v any) *messages.SettingsItem {
-		return &messages.SettingsItem{
-			Key:      k,
-			Value:    v,
-			Editable: a.ctx.Config().IsEditable(k),
+		setting := &messages.SettingsItem{
+			Key:             k,
+			Value:           v,
+			Editable:        a.ctx.Config().IsEditable(k),
+			Sensitive:       a.IsSensitive(k),
+			RestartRequired: a.RequiresRestart(k),
+			Version:         SettingVersion(v),
 		}
+		a.applySource(setting, layers)
+		a.applyLock(setting, locks)
+		return setting
 	})
 
 	sort.Slice(settings, func(i, j int) bool {
@@ -76,48 +261,237 @@ func (a *AdminSettingsService) GetSettings() []*messages.SettingsItem {
 	return settings
 }
 func (a *AdminSettingsService) GetSetting(key string) *messages.SettingsItem {
-	exists := a.ctx.Config().Exists(key)
+	value, exists := a.settingValue(key)
 	if !exists {
 		return nil
 	}
-	return &messages.SettingsItem{
-		Key:   key,
-		Value: a.ctx.Config().Get(key),
+	setting := &messages.SettingsItem{
+		Key:             key,
+		Value:           value,
+		Editable:        a.ctx.Config().IsEditable(a.settingBaseKey(key)),
+		Sensitive:       a.IsSensitive(key),
+		RestartRequired: a.RequiresRestart(key),
+		Version:         SettingVersion(value),
+	}
+	a.applySource(setting, a.loadLayers())
+
+	locks, err := a.activeLocks()
+	if err != nil {
+		a.ctx.Logger().Error("failed to load setting locks", zap.Error(err))
 	}
+	a.applyLock(setting, locks)
+
+	return setting
+}
+
+// settingWriteAttempts bounds how often a write is validated again after losing a race with another write.
+const settingWriteAttempts = 3
+
+// UpdateSetting normalizes and applies the setting on behalf of the actor, a user ID or 0 for the system.
+// When setting.Version is set, the update only succeeds if the current value still has that version,
+// otherwise ErrVersionMismatch is returned. Settings under a protected prefix return ErrApprovalRequired
+// unless the actor is the system.
+func (a *AdminSettingsService) UpdateSetting(actor uint, setting *messages.SettingsItem) error {
+	_, err := a.updateSetting(a.AccessFor(actor), setting)
+	return err
+}
+
+func (a *AdminSettingsService) updateSetting(access *SettingsAccess, setting *messages.SettingsItem) (*settingWrite, error) {
+	baseKey := a.settingBaseKey(setting.Key)
+	if !access.CanWrite(baseKey) {
+		return nil, ErrPermissionDenied
+	}
+
+	if access.Actor() != 0 && a.RequiresApproval(baseKey) {
+		return nil, ErrApprovalRequired
+	}
+
+	return a.writeSetting(access.Actor(), setting)
+}
+
+// settingWrite is a normalized and validated update, tied to the version of the config key it was
+// validated against.
+type settingWrite struct {
+	setting     *messages.SettingsItem
+	key         string
+	value       any
+	baseKey     string
+	baseVersion string
 }
 
-func (a *AdminSettingsService) UpdateSetting(setting *messages.SettingsItem) error {
+// settingValue returns the value the write leaves at the setting's key. Element and field updates rebuild
+// the whole setting, so this may be a part of the value written.
+func (w *settingWrite) settingValue() any {
+	if w.key == w.setting.Key {
+		return w.value
+	}
+	value, _ := internal.LookupPath(w.value, strings.Split(w.setting.Key[len(w.key)+1:], "."))
+	return value
+}
+
+// writeSetting applies the setting without checking permissions or approval. Locks are still enforced.
+// Validators run before updateMu is taken, so it must be called without holding it. If another write
+// changes the setting in the meantime, the update is validated again against the new value.
+func (a *AdminSettingsService) writeSetting(actor uint, setting *messages.SettingsItem) (*settingWrite, error) {
+	for attempt := 1; ; attempt++ {
+		write, err := a.validateWrite(setting)
+		if err != nil {
+			return nil, err
+		}
+
+		a.updateMu.Lock()
+		err = a.commitWrite(actor, write)
+		a.updateMu.Unlock()
+
+		if err == nil {
+			return write, nil
+		}
+		if !errors.Is(err, ErrVersionMismatch) || attempt == settingWriteAttempts {
+			return nil, err
+		}
+	}
+}
+
+// validateWrite normalizes the setting and runs the schema checks and validators on it, without holding
+// updateMu, since validators may take a while to reach other services.
+func (a *AdminSettingsService) validateWrite(setting *messages.SettingsItem) (*settingWrite, error) {
+	baseKey := a.settingBaseKey(setting.Key)
+	if err := a.checkSettingLock(baseKey); err != nil {
+		return nil, err
+	}
+
+	baseVersion := SettingVersion(a.ctx.Config().Get(baseKey))
+
+	if setting.Version != "" {
+		current, _ := a.settingValue(setting.Key)
+		if !VersionMatches(setting.Version, SettingVersion(current)) {
+			return nil, ErrVersionMismatch
+		}
+	}
+
+	key, value, err := a.prepareUpdate(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkSettingSchema(key, value); err != nil {
+		return nil, err
+	}
+
+	if err := a.runValidators(setting.Key, key, value); err != nil {
+		return nil, err
+	}
+
+	return &settingWrite{
+		setting:     setting,
+		key:         key,
+		value:       value,
+		baseKey:     baseKey,
+		baseVersion: baseVersion,
+	}, nil
+}
+
+// commitWrite applies a validated update. It returns ErrVersionMismatch if the setting changed after it was
+// validated. It must be called with updateMu held.
+func (a *AdminSettingsService) commitWrite(actor uint, write *settingWrite) error {
+	if err := a.checkSettingLock(write.baseKey); err != nil {
+		return err
+	}
+
+	if SettingVersion(a.ctx.Config().Get(write.baseKey)) != write.baseVersion {
+		return ErrVersionMismatch
+	}
+
+	oldValue := a.ctx.Config().Get(write.key)
+	if err := a.ctx.Config().Update(write.key, write.value); err != nil {
+		return err
+	}
+
+	a.schemaCheckedAt.Store(0)
+	a.trackRestart(write.key, oldValue, write.value)
+	a.recordRuntimeEdit(write.key, write.value)
+	a.publishChange(write.key, write.value, actor, SettingsEventSourceAdmin)
+	return nil
+}
+
+// ValidateSetting runs the same checks as UpdateSetting without applying the change,
+// returning the normalized value that would be stored.
+func (a *AdminSettingsService) ValidateSetting(actor uint, setting *messages.SettingsItem) (any, error) {
+	baseKey := a.settingBaseKey(setting.Key)
+	if !a.ctx.Config().Exists(baseKey) {
+		return nil, ErrSettingNotFound
+	}
+	if !a.ctx.Config().IsEditable(baseKey) {
+		return nil, ErrSettingNotEditable
+	}
+	if !a.CanWrite(actor, baseKey) {
+		return nil, ErrPermissionDenied
+	}
+
+	write, err := a.validateWrite(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	return write.settingValue(), nil
+}
+
+// prepareUpdate normalizes the setting and returns the key and value to pass to the config update.
+// Masked secrets sent back unchanged keep their current values.
+func (a *AdminSettingsService) prepareUpdate(setting *messages.SettingsItem) (string, any, error) {
+	current, _ := a.settingValue(setting.Key)
+	newValue := a.restoreRedacted(setting.Key, current, setting.Value)
+
 	parts := strings.Split(setting.Key, ".")
 	if len(parts) > 1 && isArrayIndex(parts[len(parts)-1]) {
 		// This is an array element update
-		return a.updateArraySetting(parts, setting.Value)
+		return a.prepareArrayUpdate(parts, newValue)
+	}
+
+	if baseKey, path := a.lookupSetting(setting.Key); len(path) > 0 {
+		// This is an update to a field inside a list, map or struct setting
+		return a.prepareNestedUpdate(baseKey, path, setting.Key, newValue)
 	}
 
 	// This is a regular setting update
 	currentValue := a.ctx.Config().Get(setting.Key)
-	normalizedValue, err := internal.NormalizeSetting(currentValue, setting.Value)
+	normalizedValue, err := a.NormalizeSetting(setting.Key, currentValue, newValue)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	return a.ctx.Config().Update(setting.Key, normalizedValue)
+	return setting.Key, normalizedValue, nil
 }
 
-func (a *AdminSettingsService) updateArraySetting(parts []string, newValue interface{}) error {
+// checkSettingSchema checks the normalized value against the constraints the setting's schema declares,
+// such as ranges, enums and patterns, including those of fields inside list, map and struct settings.
+func (a *AdminSettingsService) checkSettingSchema(key string, value any) error {
+	keySchema := a.settingSchema(key)
+	if keySchema == nil {
+		return nil
+	}
+
+	if err := keySchema.Validate(value); err != nil {
+		return &SettingValidationError{Key: key, Errors: []string{err.Error()}}
+	}
+	return nil
+}
+
+func (a *AdminSettingsService) prepareArrayUpdate(parts []string, newValue interface{}) (string, any, error) {
 	arrayKey := strings.Join(parts[:len(parts)-1], ".")
 	index, err := strconv.Atoi(parts[len(parts)-1])
 	if err != nil {
-		return fmt.Errorf("invalid array index: %v", err)
+		return "", nil, fmt.Errorf("invalid array index: %v", err)
 	}
 
 	currentArray := a.ctx.Config().Get(arrayKey)
 	arrayValue := reflect.ValueOf(currentArray)
 
 	if arrayValue.Kind() != reflect.Slice {
-		return fmt.Errorf("setting is not an array: %s", arrayKey)
+		return "", nil, fmt.Errorf("setting is not an array: %s", arrayKey)
 	}
 
 	if index < 0 || index >= arrayValue.Len() {
-		return fmt.Errorf("array index out of bounds: %d", index)
+		return "", nil, fmt.Errorf("array index out of bounds: %d", index)
 	}
 
 	// Create a new slice and copy the current values
@@ -125,14 +499,153 @@ func (a *AdminSettingsService) updateArraySetting(parts []string, newValue inter
 	reflect.Copy(newArrayValue, arrayValue)
 
 	// Update the specific element
-	normalizedValue, err := internal.NormalizeSetting(arrayValue.Index(index).Interface(), newValue)
+	normalizedValue, err := a.NormalizeSetting(strings.Join(parts, "."), arrayValue.Index(index).Interface(), newValue)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 	newArrayValue.Index(index).Set(reflect.ValueOf(normalizedValue))
 
-	// Update the entire array setting
-	return a.ctx.Config().Update(arrayKey, newArrayValue.Interface())
+	return arrayKey, newArrayValue.Interface(), nil
+}
+
+// prepareNestedUpdate replaces a field or element inside a setting, returning the whole setting with the
+// change applied.
+func (a *AdminSettingsService) prepareNestedUpdate(baseKey string, path []string, key string, newValue any) (string, any, error) {
+	updated, err := internal.SetPath(a.ctx.Config().Get(baseKey), path, func(current any) (any, error) {
+		return a.NormalizeSetting(key, current, newValue)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return baseKey, updated, nil
+}
+
+// NormalizeSetting converts the new value to the type of the current one, accepting human-friendly units
+// when the setting's schema declares a format for them.
+func (a *AdminSettingsService) NormalizeSetting(key string, currentValue any, newValue any) (any, error) {
+	return internal.NormalizeSettingWithFormat(currentValue, newValue, a.settingFormat(key))
+}
+
+// FormatSetting fills in the human-friendly form of the setting's value, if its format has one.
+func (a *AdminSettingsService) FormatSetting(setting *messages.SettingsItem) *messages.SettingsItem {
+	if setting.Sensitive {
+		return setting
+	}
+	if formatted, ok := internal.FormatSetting(setting.Value, a.settingFormat(setting.Key)); ok {
+		setting.Formatted = formatted
+	}
+	return setting
+}
+
+func (a *AdminSettingsService) settingFormat(key string) string {
+	if keySchema := schemaForKey(a.GetSchema(), key); keySchema != nil {
+		return keySchema.Format
+	}
+	return ""
+}
+
+// settingSchema returns the self-contained schema of a setting, including keys inside list, map and
+// struct settings, or nil if the schema does not describe it.
+func (a *AdminSettingsService) settingSchema(key string) *schema.Schema {
+	root := a.GetSchema()
+	keySchema := schemaForKey(root, key)
+	if keySchema == nil {
+		return nil
+	}
+	return keySchema.Inline(root)
+}
+
+// schemaForKey resolves a dotted setting key to its sub-schema, following array items for numeric segments
+// and references into $defs.
+func schemaForKey(root *schema.Schema, key string) *schema.Schema {
+	current := root
+	for _, part := range strings.Split(key, ".") {
+		current = childSchema(current.Resolve(root), part)
+		if current == nil {
+			return nil
+		}
+	}
+	return current.Resolve(root)
+}
+
+// schemaWriteOnly reports whether the schema marks the value at key, or any value containing it, as write
+// only. Unlike the sensitive paths recorded while building the schema, it matches every list index and map key.
+func schemaWriteOnly(root *schema.Schema, key string) bool {
+	current := root
+	for _, part := range strings.Split(key, ".") {
+		current = childSchema(current.Resolve(root), part)
+		if current == nil {
+			return false
+		}
+		// Annotations next to a $ref are kept on the reference itself
+		if resolved := current.Resolve(root); current.WriteOnly || (resolved != nil && resolved.WriteOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// childSchema returns the unresolved schema of a property, list element or map value.
+func childSchema(parent *schema.Schema, part string) *schema.Schema {
+	if parent == nil {
+		return nil
+	}
+
+	if parent.Properties != nil {
+		if next, exists := parent.Properties.Get(part); exists {
+			return next
+		}
+	}
+
+	switch {
+	case parent.Items != nil && isArrayIndex(part):
+		return parent.Items
+	case parent.AdditionalProperties != nil:
+		return parent.AdditionalProperties
+	}
+	return nil
+}
+
+// settingBaseKey returns the config key holding the setting, stripping any array index or nested field
+// path from the key.
+func (a *AdminSettingsService) settingBaseKey(key string) string {
+	if baseKey, _ := a.lookupSetting(key); baseKey != "" {
+		return baseKey
+	}
+	return key
+}
+
+// lookupSetting splits a key into the longest prefix that exists in the config and the path into its
+// value that remains, which is empty if the key exists as a whole. It returns an empty key if no prefix exists.
+func (a *AdminSettingsService) lookupSetting(key string) (string, []string) {
+	if a.ctx.Config().Exists(key) {
+		return key, nil
+	}
+
+	parts := strings.Split(key, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		baseKey := strings.Join(parts[:i], ".")
+		if a.ctx.Config().Exists(baseKey) {
+			return baseKey, parts[i:]
+		}
+	}
+
+	return "", nil
+}
+
+// settingValue returns the value of a setting, following array indexes and field names into list, map
+// and struct settings.
+func (a *AdminSettingsService) settingValue(key string) (any, bool) {
+	baseKey, path := a.lookupSetting(key)
+	if baseKey == "" {
+		return nil, false
+	}
+
+	value := a.ctx.Config().Get(baseKey)
+	if len(path) == 0 {
+		return value, true
+	}
+	return internal.LookupPath(value, path)
 }
 
 func isArrayIndex(s string) bool {
@@ -140,9 +653,27 @@ func isArrayIndex(s string) bool {
 	return err == nil
 }
 
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	timeType          = reflect.TypeOf(time.Time{})
+	yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+)
+
+// defaultsProvider is implemented by config structs that declare default values, keyed relative to the struct.
+type defaultsProvider interface {
+	Defaults() map[string]any
+}
+
 type schemaBuilder struct {
-	schema *schema.Schema
-	ctx    core.Context
+	schema    *schema.Schema
+	ctx       core.Context
+	sensitive map[string]bool
+	restart   map[string]bool
+	defaults  map[string]any
+	visiting  map[reflect.Type]bool
+	// structUses holds every object schema built from a named struct type, so repeated ones can be
+	// moved to $defs once the whole config has been walked
+	structUses map[reflect.Type][]*schema.Schema
 }
 
 func (sb *schemaBuilder) buildSchema(_ *reflect.StructField, field reflect.StructField, value reflect.Value, prefix string) error {
@@ -153,8 +684,24 @@ func (sb *schemaBuilder) buildSchema(_ *reflect.StructField, field reflect.Struc
 		return nil
 	}
 
+	if field.Tag.Get(sensitiveTag) == "true" {
+		sb.sensitive[fullPath] = true
+	}
+	if field.Tag.Get(restartTag) == "true" {
+		sb.restart[fullPath] = true
+	}
+
+	sb.collectDefaults(value, fullPath)
+
 	fieldSchema := sb.getFieldSchema(field.Type, value, fullPath)
 	if fieldSchema != nil {
+		applyFieldTags(fieldSchema, field)
+		if sb.sensitive[fullPath] || isSensitiveName(fieldName) {
+			fieldSchema.WriteOnly = true
+		}
+		if sb.restart[fullPath] || requiresRestart(fullPath) {
+			markRestartRequired(fieldSchema)
+		}
 		sb.setSchemaProperty(fullPath, fieldSchema)
 	}
 
@@ -162,11 +709,15 @@ func (sb *schemaBuilder) buildSchema(_ *reflect.StructField, field reflect.Struc
 }
 
 func (sb *schemaBuilder) getFieldSchema(field reflect.Type, v reflect.Value, path string) *schema.Schema {
+	if field == nil {
+		return nil
+	}
+
 	_schema := &schema.Schema{}
 
 	checkReadyOnly := false
 
-	switch v.Kind() {
+	switch field.Kind() {
 	case reflect.Bool:
 		_schema.Type = "boolean"
 		checkReadyOnly = true
@@ -174,6 +725,9 @@ func (sb *schemaBuilder) getFieldSchema(field reflect.Type, v reflect.Value, pat
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		_schema.Type = "integer"
+		if field == durationType {
+			_schema.Format = internal.FormatDuration
+		}
 		checkReadyOnly = true
 	case reflect.Float32, reflect.Float64:
 		_schema.Type = "number"
@@ -183,34 +737,66 @@ func (sb *schemaBuilder) getFieldSchema(field reflect.Type, v reflect.Value, pat
 		checkReadyOnly = true
 	case reflect.Slice, reflect.Array:
 		_schema.Type = "array"
-		if v.Len() > 0 {
-			fullPath := buildFullPath(path, strconv.Itoa(0))
-			_schema.Items = sb.getFieldSchema(v.Index(0).Type(), v.Index(0), fullPath)
+		if field.Kind() == reflect.Array {
+			length := uint64(field.Len())
+			_schema.MinItems = &length
+			_schema.MaxItems = &length
+		}
+
+		// A sample element is only needed when the element type alone does not say enough, e.g. []any
+		var sample reflect.Value
+		itemPath := path
+		if v.IsValid() && v.Len() > 0 {
+			sample = v.Index(0)
+			itemPath = buildFullPath(path, strconv.Itoa(0))
 		}
+		_schema.Items = sb.getElemSchema(field.Elem(), sample, itemPath)
 	case reflect.Map:
 		_schema.Type = "object"
-		_schema.AdditionalProperties = &schema.Schema{}
-		if v.Len() > 0 {
-			for _, key := range v.MapKeys() {
-				fullPath := buildFullPath(path, key.String())
-				_schema.AdditionalProperties = sb.getFieldSchema(v.MapIndex(key).Type(), v.MapIndex(key), fullPath)
-				break
-			}
+		_schema.PropertyNames = mapKeySchema(field.Key())
+
+		var sample reflect.Value
+		valuePath := path
+		if v.IsValid() && v.Len() > 0 {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+			})
+			sample = v.MapIndex(keys[0])
+			valuePath = buildFullPath(path, fmt.Sprint(keys[0].Interface()))
 		}
-	case reflect.Struct, reflect.Interface:
+		_schema.AdditionalProperties = sb.getElemSchema(field.Elem(), sample, valuePath)
+	case reflect.Struct:
+		if field == timeType {
+			_schema.Type = "string"
+			_schema.Format = "date-time"
+			checkReadyOnly = true
+			break
+		}
+
 		// Check if the struct implements MarshalYAML
-		if marshaler, ok := v.Interface().(yaml.Marshaler); ok {
-			yamlData, err := marshaler.MarshalYAML()
-			if err == nil {
-				return sb.handleYAMLMarshaled(yamlData)
-			}
+		if marshaled := sb.getMarshaledSchema(v); marshaled != nil {
+			return marshaled
 		}
 
+		// Struct fields are visited individually by the config field processor
 		return nil
+	case reflect.Interface:
+		if !v.IsValid() || v.IsNil() {
+			return nil
+		}
+
+		if marshaled := sb.getMarshaledSchema(v); marshaled != nil {
+			return marshaled
+		}
+
+		return sb.getFieldSchema(v.Elem().Type(), v.Elem(), path)
 	case reflect.Ptr:
-		if !v.IsNil() {
-			return sb.getFieldSchema(field, v.Elem(), path)
+		var elem reflect.Value
+		if v.IsValid() && !v.IsNil() {
+			elem = v.Elem()
 		}
+		return sb.getFieldSchema(field.Elem(), elem, path)
 	}
 
 	if checkReadyOnly {
@@ -222,6 +808,191 @@ func (sb *schemaBuilder) getFieldSchema(field reflect.Type, v reflect.Value, pat
 	return _schema
 }
 
+// getElemSchema describes the elements of a list or the values of a map from their type. Struct elements
+// are described field by field, and elements of interface type fall back to the sample value if there is
+// one, or accept anything otherwise.
+func (sb *schemaBuilder) getElemSchema(elem reflect.Type, sample reflect.Value, path string) *schema.Schema {
+	structType := elem
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() == reflect.Struct && structType != timeType && !implementsYAMLMarshaler(elem) {
+		return sb.getStructSchema(structType, path)
+	}
+
+	if elemSchema := sb.getFieldSchema(elem, sample, path); elemSchema != nil {
+		return elemSchema
+	}
+
+	return &schema.Schema{}
+}
+
+// getStructSchema describes a struct type as an object, naming properties the same way as config fields.
+func (sb *schemaBuilder) getStructSchema(t reflect.Type, path string) *schema.Schema {
+	_schema := &schema.Schema{
+		Type:       "object",
+		Properties: orderedmap.New[string, *schema.Schema](),
+	}
+
+	if sb.visiting[t] {
+		// Recursive type, leave the nested occurrence open
+		return _schema
+	}
+	sb.visiting[t] = true
+	defer delete(sb.visiting, t)
+
+	fields := internal.StructFields(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := sb.getStructSchema(field.Type, path)
+			for pair := embedded.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				// Direct fields shadow promoted ones, as in Go, wherever they are declared
+				if index, ok := fields[pair.Key]; ok && len(index) == 1 {
+					continue
+				}
+				if _, exists := _schema.Properties.Get(pair.Key); exists {
+					continue
+				}
+				_schema.Properties.Set(pair.Key, pair.Value)
+			}
+			continue
+		}
+
+		fieldName := getFieldName(field)
+		if fieldName == "-" {
+			continue
+		}
+
+		fieldPath := buildFullPath(path, fieldName)
+		fieldSchema := sb.getElemSchema(field.Type, reflect.Value{}, fieldPath)
+
+		applyFieldTags(fieldSchema, field)
+		if field.Tag.Get(sensitiveTag) == "true" || isSensitiveName(fieldName) {
+			fieldSchema.WriteOnly = true
+			sb.sensitive[fieldPath] = true
+		}
+		if field.Tag.Get(restartTag) == "true" {
+			sb.restart[fieldPath] = true
+			markRestartRequired(fieldSchema)
+		}
+
+		_schema.Properties.Set(fieldName, fieldSchema)
+	}
+
+	if t.Name() != "" {
+		sb.structUses[t] = append(sb.structUses[t], _schema)
+	}
+
+	return _schema
+}
+
+// hoistDefinitions moves struct types that are described more than once into the root $defs and replaces
+// each occurrence with a $ref. Occurrences whose descriptions differ, for example because only some of
+// them are editable, are left inline.
+func (sb *schemaBuilder) hoistDefinitions() {
+	types := make([]reflect.Type, 0, len(sb.structUses))
+	for t, uses := range sb.structUses {
+		if len(uses) > 1 {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].PkgPath()+"."+types[i].Name() < types[j].PkgPath()+"."+types[j].Name()
+	})
+
+	for _, t := range types {
+		uses := sb.structUses[t]
+		if !sameStructSchemas(uses) {
+			continue
+		}
+
+		name := sb.definitionName(t)
+		if sb.schema.Definitions == nil {
+			sb.schema.Definitions = make(schema.Definitions)
+		}
+		sb.schema.Definitions[name] = &schema.Schema{
+			Type:       uses[0].Type,
+			Properties: uses[0].Properties,
+		}
+
+		for _, use := range uses {
+			use.Type = ""
+			use.Properties = nil
+			use.Ref = schema.DefinitionRef(name)
+		}
+	}
+}
+
+// definitionName names a struct type in $defs, qualifying it with its package if the plain name is taken.
+func (sb *schemaBuilder) definitionName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := sb.schema.Definitions[name]; taken {
+		name = strcase.UpperCamelCase(path.Base(t.PkgPath())) + name
+	}
+	return name
+}
+
+func sameStructSchemas(uses []*schema.Schema) bool {
+	var first []byte
+	for i, use := range uses {
+		body, err := json.Marshal(&schema.Schema{Type: use.Type, Properties: use.Properties})
+		if err != nil {
+			return false
+		}
+		if i == 0 {
+			first = body
+		} else if !bytes.Equal(first, body) {
+			return false
+		}
+	}
+	return true
+}
+
+// getMarshaledSchema describes a value through its YAML representation, if it implements yaml.Marshaler.
+func (sb *schemaBuilder) getMarshaledSchema(v reflect.Value) *schema.Schema {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	marshaler, ok := v.Interface().(yaml.Marshaler)
+	if !ok {
+		return nil
+	}
+
+	yamlData, err := marshaler.MarshalYAML()
+	if err != nil {
+		return nil
+	}
+
+	return sb.handleYAMLMarshaled(yamlData)
+}
+
+// mapKeySchema constrains the property names of maps whose keys are not strings.
+func mapKeySchema(key reflect.Type) *schema.Schema {
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &schema.Schema{Type: "string", Pattern: "^-?[0-9]+$"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema.Schema{Type: "string", Pattern: "^[0-9]+$"}
+	case reflect.Float32, reflect.Float64:
+		return &schema.Schema{Type: "string", Pattern: "^-?[0-9]+(\\.[0-9]+)?$"}
+	case reflect.Bool:
+		return &schema.Schema{Type: "string", Enum: []any{"true", "false"}}
+	}
+	return nil
+}
+
+func implementsYAMLMarshaler(t reflect.Type) bool {
+	return t.Implements(yamlMarshalerType) || reflect.PointerTo(t).Implements(yamlMarshalerType)
+}
+
 func (sb *schemaBuilder) handleYAMLMarshaled(data interface{}) *schema.Schema {
 	_schema := &schema.Schema{}
 
@@ -245,6 +1016,49 @@ func (sb *schemaBuilder) handleYAMLMarshaled(data interface{}) *schema.Schema {
 	return _schema
 }
 
+// collectDefaults records the defaults declared by a config struct, keyed by their full path.
+func (sb *schemaBuilder) collectDefaults(v reflect.Value, prefix string) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) || !v.CanInterface() {
+		return
+	}
+
+	provider, ok := v.Interface().(defaultsProvider)
+	if !ok {
+		return
+	}
+
+	for key, value := range provider.Defaults() {
+		sb.defaults[buildFullPath(prefix, key)] = value
+	}
+}
+
+// applyDefaults copies the collected defaults onto the matching schema properties.
+func (sb *schemaBuilder) applyDefaults() {
+	for path, value := range sb.defaults {
+		if propSchema := sb.getSchemaProperty(path); propSchema != nil {
+			propSchema.Default = value
+		}
+	}
+}
+
+func (sb *schemaBuilder) getSchemaProperty(path string) *schema.Schema {
+	current := sb.schema
+	for _, part := range strings.Split(path, ".") {
+		if current == nil || current.Properties == nil {
+			return nil
+		}
+		next, exists := current.Properties.Get(part)
+		if !exists {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
 func (sb *schemaBuilder) setSchemaProperty(path string, _schema *schema.Schema) {
 	parts := strings.Split(path, ".")
 	current := sb.schema
@@ -272,10 +1086,7 @@ func (sb *schemaBuilder) setSchemaProperty(path string, _schema *schema.Schema)
 }
 
 func getFieldName(field reflect.StructField) string {
-	if configTag := field.Tag.Get("config"); configTag != "" {
-		return configTag
-	}
-	return strcase.SnakeCase(field.Name)
+	return internal.FieldName(field)
 }
 
 func buildFullPath(prefix, fieldName string) string {
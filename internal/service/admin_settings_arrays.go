@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"reflect"
+)
+
+const (
+	ArrayEditAppend = "append"
+	ArrayEditInsert = "insert"
+	ArrayEditRemove = "remove"
+	ArrayEditMove   = "move"
+)
+
+var ErrSettingNotArray = errors.New("setting is not an array")
+
+// EditArraySetting appends, inserts, removes or moves a single element of a list setting and applies the
+// resulting list through the normal update path. New elements are checked against the list's item schema
+// and converted to the element type. A non-empty version must match the setting's current version, and
+// the edit fails with ErrVersionMismatch if the list changes before it is applied.
+func (a *AdminSettingsService) EditArraySetting(actor uint, key string, edit *messages.SettingArrayEdit, version string) error {
+	baseKey := a.settingBaseKey(key)
+	if !a.ctx.Config().Exists(baseKey) {
+		return ErrSettingNotFound
+	}
+	if !a.ctx.Config().IsEditable(baseKey) {
+		return ErrSettingNotEditable
+	}
+
+	current, ok := a.settingValue(key)
+	if !ok {
+		return ErrSettingNotFound
+	}
+	if !VersionMatches(version, SettingVersion(current)) {
+		return ErrVersionMismatch
+	}
+
+	updated, err := a.applyArrayEdit(key, current, edit)
+	if err != nil {
+		return err
+	}
+
+	// Pin the update to the list the edit was made on
+	_, err = a.updateSetting(a.AccessFor(actor), &messages.SettingsItem{Key: key, Value: updated, Version: SettingVersion(current)})
+	return err
+}
+
+func (a *AdminSettingsService) applyArrayEdit(key string, current any, edit *messages.SettingArrayEdit) (any, error) {
+	array := reflect.ValueOf(current)
+	if array.Kind() != reflect.Slice {
+		return nil, ErrSettingNotArray
+	}
+	length := array.Len()
+
+	switch edit.Op {
+	case ArrayEditAppend:
+		return a.insertArrayElement(key, array, length, edit.Value)
+	case ArrayEditInsert:
+		if edit.Index < 0 || edit.Index > length {
+			return nil, fmt.Errorf("array index out of bounds: %d", edit.Index)
+		}
+		return a.insertArrayElement(key, array, edit.Index, edit.Value)
+	case ArrayEditRemove:
+		if edit.Index < 0 || edit.Index >= length {
+			return nil, fmt.Errorf("array index out of bounds: %d", edit.Index)
+		}
+		updated := reflect.MakeSlice(array.Type(), 0, length-1)
+		updated = reflect.AppendSlice(updated, array.Slice(0, edit.Index))
+		updated = reflect.AppendSlice(updated, array.Slice(edit.Index+1, length))
+		return a.checkArrayLength(key, updated)
+	case ArrayEditMove:
+		if edit.Index < 0 || edit.Index >= length {
+			return nil, fmt.Errorf("array index out of bounds: %d", edit.Index)
+		}
+		if edit.To < 0 || edit.To >= length {
+			return nil, fmt.Errorf("array index out of bounds: %d", edit.To)
+		}
+		rest := reflect.MakeSlice(array.Type(), 0, length-1)
+		rest = reflect.AppendSlice(rest, array.Slice(0, edit.Index))
+		rest = reflect.AppendSlice(rest, array.Slice(edit.Index+1, length))
+
+		updated := reflect.MakeSlice(array.Type(), 0, length)
+		updated = reflect.AppendSlice(updated, rest.Slice(0, edit.To))
+		updated = reflect.Append(updated, array.Index(edit.Index))
+		updated = reflect.AppendSlice(updated, rest.Slice(edit.To, length-1))
+		return updated.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("unknown array operation: %s", edit.Op)
+}
+
+// insertArrayElement validates the new element against the item schema, converts it to the element type
+// and returns a copy of the array with the element inserted at index.
+func (a *AdminSettingsService) insertArrayElement(key string, array reflect.Value, index int, value any) (any, error) {
+	if itemSchema := a.arrayItemSchema(key); itemSchema != nil {
+		if err := itemSchema.Validate(value); err != nil {
+			return nil, err
+		}
+	}
+
+	elemKey := key + "." + fmt.Sprint(index)
+	element, err := a.NormalizeSetting(elemKey, reflect.Zero(array.Type().Elem()).Interface(), value)
+	if err != nil {
+		return nil, err
+	}
+
+	elemValue := reflect.ValueOf(element)
+	if !elemValue.IsValid() {
+		elemValue = reflect.Zero(array.Type().Elem())
+	}
+
+	updated := reflect.MakeSlice(array.Type(), 0, array.Len()+1)
+	updated = reflect.AppendSlice(updated, array.Slice(0, index))
+	updated = reflect.Append(updated, elemValue)
+	updated = reflect.AppendSlice(updated, array.Slice(index, array.Len()))
+
+	return a.checkArrayLength(key, updated)
+}
+
+// checkArrayLength enforces the minItems and maxItems of the list's schema on the edited list.
+func (a *AdminSettingsService) checkArrayLength(key string, updated reflect.Value) (any, error) {
+	if arraySchema := a.settingSchema(key); arraySchema != nil {
+		bounds := &schema.Schema{MinItems: arraySchema.MinItems, MaxItems: arraySchema.MaxItems, Type: "array"}
+		if err := bounds.Validate(updated.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return updated.Interface(), nil
+}
+
+func (a *AdminSettingsService) arrayItemSchema(key string) *schema.Schema {
+	if arraySchema := a.settingSchema(key); arraySchema != nil {
+		return arraySchema.Items
+	}
+	return nil
+}
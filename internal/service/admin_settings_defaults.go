@@ -0,0 +1,109 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GetDefault returns the default value declared for the setting, if any.
+func (a *AdminSettingsService) GetDefault(key string) (any, bool) {
+	value, ok := a.currentSchema().defaults[key]
+	return value, ok
+}
+
+// ResetSetting restores a single setting to its default through the normal update path. A non-empty
+// version must match the setting's current version.
+func (a *AdminSettingsService) ResetSetting(actor uint, key string, version string) error {
+	if !a.ctx.Config().Exists(key) {
+		return ErrSettingNotFound
+	}
+	if !a.ctx.Config().IsEditable(key) {
+		return ErrSettingNotEditable
+	}
+
+	value, ok := a.GetDefault(key)
+	if !ok {
+		return ErrSettingNoDefault
+	}
+
+	return a.UpdateSetting(actor, &messages.SettingsItem{Key: key, Value: value, Version: version})
+}
+
+// ResetSettings restores every setting under the prefix that has a default and differs from it. Defaults
+// are validated before updateMu is taken, and a setting modified in the meantime fails with
+// ErrVersionMismatch. A non-empty version must match GetSettingsVersion.
+func (a *AdminSettingsService) ResetSettings(actor uint, prefix string, version string) ([]*messages.SettingsChange, error) {
+	if !VersionMatches(version, a.GetSettingsVersion()) {
+		return nil, ErrVersionMismatch
+	}
+
+	all := a.ctx.Config().All()
+	access := a.AccessFor(actor)
+
+	keys := make([]string, 0)
+	for key := range all {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*messages.SettingsChange, 0, len(keys))
+	writes := make(map[*messages.SettingsChange]*settingWrite)
+	for _, key := range keys {
+		value, ok := a.GetDefault(key)
+		if !ok || reflect.DeepEqual(all[key], value) {
+			continue
+		}
+
+		change := &messages.SettingsChange{
+			Key:      key,
+			OldValue: all[key],
+			NewValue: value,
+			Editable: a.ctx.Config().IsEditable(key),
+			Status:   SettingChangeStatusApplied,
+		}
+
+		if !change.Editable {
+			change.Status = SettingChangeStatusSkipped
+			change.Error = ErrSettingNotEditable.Error()
+		} else if !access.CanWrite(key) {
+			change.Status = SettingChangeStatusSkipped
+			change.Error = ErrPermissionDenied.Error()
+		} else if access.Actor() != 0 && a.RequiresApproval(key) {
+			setChangeError(change, ErrApprovalRequired)
+		} else if write, err := a.validateWrite(&messages.SettingsItem{Key: key, Value: value}); err != nil {
+			setChangeError(change, err)
+		} else {
+			writes[change] = write
+		}
+
+		changes = append(changes, change)
+	}
+
+	if len(writes) > 0 {
+		a.updateMu.Lock()
+		defer a.updateMu.Unlock()
+
+		if !VersionMatches(version, a.GetSettingsVersion()) {
+			return nil, ErrVersionMismatch
+		}
+
+		for _, change := range changes {
+			if write, ok := writes[change]; ok {
+				if err := a.commitWrite(actor, write); err != nil {
+					setChangeError(change, err)
+				}
+			}
+		}
+	}
+
+	for _, change := range changes {
+		change.OldValue = a.RedactValue(change.Key, change.OldValue)
+		change.NewValue = a.RedactValue(change.Key, change.NewValue)
+	}
+
+	return changes, nil
+}
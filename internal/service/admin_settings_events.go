@@ -0,0 +1,172 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"strings"
+	"time"
+)
+
+const (
+	SettingsEventSourceAdmin    = "admin"
+	SettingsEventSourceExternal = "external"
+)
+
+const (
+	// settingsWatchInterval is how often the running config is polled for changes made outside the admin API
+	settingsWatchInterval = 5 * time.Second
+	settingsEventBuffer   = 32
+)
+
+// SubscribeChanges returns a channel receiving an event for every settings change, along with a function
+// to unsubscribe. While anyone is subscribed, the running config is also watched for external changes.
+// Slow subscribers miss events rather than blocking updates.
+func (a *AdminSettingsService) SubscribeChanges() (<-chan *messages.SettingsEvent, func()) {
+	ch := make(chan *messages.SettingsEvent, settingsEventBuffer)
+
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+
+	a.subscribers[ch] = struct{}{}
+	if len(a.subscribers) == 1 {
+		a.seenVersions = a.currentVersions()
+		a.watchStop = make(chan struct{})
+		go a.watchChanges(a.watchStop)
+	}
+
+	unsubscribe := func() {
+		a.eventsMu.Lock()
+		defer a.eventsMu.Unlock()
+
+		if _, ok := a.subscribers[ch]; !ok {
+			return
+		}
+
+		delete(a.subscribers, ch)
+		close(ch)
+
+		if len(a.subscribers) == 0 {
+			close(a.watchStop)
+			a.seenVersions = nil
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (a *AdminSettingsService) publishChange(key string, value any, actor uint, source string) {
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+
+	if len(a.subscribers) == 0 {
+		return
+	}
+
+	// Our own update is already announced, so the watcher should not report it again. Only the written key
+	// is marked as seen, so external changes to other keys since the last poll are still reported.
+	a.markSeen(key)
+
+	a.broadcast(&messages.SettingsEvent{
+		Key:       key,
+		Value:     a.RedactValue(key, value),
+		Actor:     actor,
+		Source:    source,
+		Timestamp: time.Now(),
+	})
+}
+
+func (a *AdminSettingsService) watchChanges(stop chan struct{}) {
+	ticker := time.NewTicker(settingsWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			a.detectExternalChanges()
+		}
+	}
+}
+
+func (a *AdminSettingsService) detectExternalChanges() {
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+
+	if a.seenVersions == nil {
+		return
+	}
+
+	all := a.ctx.Config().All()
+	now := time.Now()
+
+	for key, value := range all {
+		version := SettingVersion(value)
+		if seen, ok := a.seenVersions[key]; ok && seen == version {
+			continue
+		}
+
+		a.seenVersions[key] = version
+		a.broadcast(&messages.SettingsEvent{
+			Key:       key,
+			Value:     a.RedactValue(key, value),
+			Source:    SettingsEventSourceExternal,
+			Timestamp: now,
+		})
+	}
+
+	for key := range a.seenVersions {
+		if _, ok := all[key]; ok {
+			continue
+		}
+
+		delete(a.seenVersions, key)
+		a.broadcast(&messages.SettingsEvent{
+			Key:       key,
+			Source:    SettingsEventSourceExternal,
+			Removed:   true,
+			Timestamp: now,
+		})
+	}
+}
+
+// markSeen records the current versions of the key and the flattened keys below it, such as the entries of
+// a map setting, and forgets entries the write removed. It must be called with eventsMu held.
+func (a *AdminSettingsService) markSeen(key string) {
+	if a.seenVersions == nil {
+		return
+	}
+
+	under := func(candidate string) bool {
+		return candidate == key || strings.HasPrefix(candidate, key+".")
+	}
+
+	for seen := range a.seenVersions {
+		if under(seen) {
+			delete(a.seenVersions, seen)
+		}
+	}
+	for current, value := range a.ctx.Config().All() {
+		if under(current) {
+			a.seenVersions[current] = SettingVersion(value)
+		}
+	}
+}
+
+// broadcast must be called with eventsMu held.
+func (a *AdminSettingsService) broadcast(event *messages.SettingsEvent) {
+	for ch := range a.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (a *AdminSettingsService) currentVersions() map[string]string {
+	all := a.ctx.Config().All()
+	versions := make(map[string]string, len(all))
+	for key, value := range all {
+		versions[key] = SettingVersion(value)
+	}
+	return versions
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"sort"
+	"testing"
+)
+
+func drainEvents(ch <-chan *messages.SettingsEvent) []*messages.SettingsEvent {
+	var events []*messages.SettingsEvent
+	for {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		default:
+			sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+			return events
+		}
+	}
+}
+
+func TestSettingsEvents(t *testing.T) {
+	a := newTestSettingsService(map[string]any{
+		"core.domain":      "example.com",
+		"core.port":        8080,
+		"core.limits.soft": 10,
+		"core.limits.hard": 20,
+	}, nil)
+	cfg := a.ctx.(*testContext).config
+
+	ch, unsubscribe := a.SubscribeChanges()
+	defer unsubscribe()
+
+	// An external change lands between polls, followed by an admin write of another key
+	cfg.set("core.port", 9090)
+	cfg.set("core.domain", "portal.example.com")
+	a.publishChange("core.domain", "portal.example.com", 1, SettingsEventSourceAdmin)
+
+	// An admin write of a map setting drops one of its entries
+	cfg.set("core.limits.hard", nil)
+	a.publishChange("core.limits", map[string]any{"soft": 10}, 1, SettingsEventSourceAdmin)
+
+	// And a key disappears outside the admin API
+	cfg.set("core.limits.soft", nil)
+
+	a.detectExternalChanges()
+
+	events := drainEvents(ch)
+	want := []struct {
+		key     string
+		source  string
+		removed bool
+	}{
+		{key: "core.domain", source: SettingsEventSourceAdmin},
+		{key: "core.limits", source: SettingsEventSourceAdmin},
+		{key: "core.limits.soft", source: SettingsEventSourceExternal, removed: true},
+		{key: "core.port", source: SettingsEventSourceExternal},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Key != want[i].key || event.Source != want[i].source || event.Removed != want[i].removed {
+			t.Errorf("event %d = %s/%s/removed=%v, want %s/%s/removed=%v", i, event.Key, event.Source, event.Removed,
+				want[i].key, want[i].source, want[i].removed)
+		}
+	}
+
+	if port := events[3].Value; port != 9090 {
+		t.Errorf("external change value = %v, want 9090", port)
+	}
+}
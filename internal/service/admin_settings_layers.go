@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Sources a setting's value can come from, from lowest to highest precedence.
+const (
+	SettingSourceDefault = "default"
+	SettingSourceFile    = "file"
+	SettingSourceEnv     = "env"
+	SettingSourceRuntime = "runtime"
+)
+
+// configEnvPrefix and configEnvSeparator describe how the portal maps environment variables to config
+// keys, e.g. PORTAL__CORE__DOMAIN sets core.domain. They must be kept in line with the env provider the
+// portal's config manager loads; a mismatch only hides the env layer, it never changes a value.
+const (
+	configEnvPrefix    = "PORTAL__"
+	configEnvSeparator = "__"
+)
+
+// configFileProvider is implemented by config managers that expose the path of the loaded config file.
+// Managers that don't are reported without a file layer.
+type configFileProvider interface {
+	ConfigFile() string
+}
+
+// configLayers holds the values each source provides, keyed by dotted setting key.
+type configLayers struct {
+	file map[string]any
+	env  map[string]any
+}
+
+// GetSettingLayers returns the full precedence chain for a setting, lowest first. The last layer is the
+// one in effect.
+func (a *AdminSettingsService) GetSettingLayers(key string) ([]*messages.SettingLayer, error) {
+	if !a.ctx.Config().Exists(key) {
+		return nil, ErrSettingNotFound
+	}
+
+	return a.settingLayers(key, a.ctx.Config().Get(key), a.loadLayers()), nil
+}
+
+// applySource fills in the setting's source and the lower-layer values it overrides.
+func (a *AdminSettingsService) applySource(setting *messages.SettingsItem, layers *configLayers) {
+	chain := a.settingLayers(setting.Key, setting.Value, layers)
+
+	setting.Source = chain[len(chain)-1].Source
+	setting.Overridden = chain[:len(chain)-1]
+}
+
+func (a *AdminSettingsService) settingLayers(key string, current any, layers *configLayers) []*messages.SettingLayer {
+	chain := make([]*messages.SettingLayer, 0, 4)
+
+	addLayer := func(source string, value any) {
+		chain = append(chain, &messages.SettingLayer{
+			Source: source,
+			Value:  a.RedactValue(key, value),
+		})
+	}
+
+	if value, ok := a.GetDefault(key); ok {
+		addLayer(SettingSourceDefault, value)
+	}
+	if value, ok := layers.file[key]; ok {
+		addLayer(SettingSourceFile, value)
+	}
+	if value, ok := layers.env[key]; ok {
+		addLayer(SettingSourceEnv, value)
+	}
+
+	a.layersMu.RLock()
+	value, ok := a.runtimeEdits[key]
+	a.layersMu.RUnlock()
+	if ok {
+		addLayer(SettingSourceRuntime, value)
+	}
+
+	if len(chain) == 0 {
+		// Nothing overrides the value the config struct was initialized with
+		addLayer(SettingSourceDefault, current)
+	}
+
+	chain[len(chain)-1].Active = true
+
+	return chain
+}
+
+// loadLayers returns the file and env layers, reading them again only when the config file has changed
+// since they were last loaded. Callers load the layers once and share them across the settings they build.
+func (a *AdminSettingsService) loadLayers() *configLayers {
+	path, stamp := a.configFileStamp()
+
+	a.layersMu.RLock()
+	layers, layersStamp := a.layers, a.layersStamp
+	a.layersMu.RUnlock()
+	if layers != nil && layersStamp == stamp {
+		return layers
+	}
+
+	layers = &configLayers{
+		file: make(map[string]any),
+		env:  readEnvLayer(),
+	}
+
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			var doc map[string]any
+			if yaml.Unmarshal(data, &doc) == nil {
+				layers.file = internal.FlattenSettings(doc)
+			}
+		}
+	}
+
+	a.layersMu.Lock()
+	a.layers, a.layersStamp = layers, stamp
+	a.layersMu.Unlock()
+
+	return layers
+}
+
+// configFileStamp identifies the current contents of the config file by path, size and modification time.
+func (a *AdminSettingsService) configFileStamp() (string, string) {
+	provider, ok := a.ctx.Config().(configFileProvider)
+	if !ok {
+		return "", ""
+	}
+
+	path := provider.ConfigFile()
+	info, err := os.Stat(path)
+	if err != nil {
+		return path, path
+	}
+	return path, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())
+}
+
+func readEnvLayer() map[string]any {
+	env := make(map[string]any)
+	for _, entry := range os.Environ() {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || !strings.HasPrefix(name, configEnvPrefix) {
+			continue
+		}
+
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, configEnvPrefix), configEnvSeparator, "."))
+		env[key] = value
+	}
+	return env
+}
+
+// recordRuntimeEdit remembers a value applied through the admin API. Map values are stored per entry so
+// they line up with the flattened keys of the running config.
+func (a *AdminSettingsService) recordRuntimeEdit(key string, value any) {
+	a.layersMu.Lock()
+	defer a.layersMu.Unlock()
+
+	for existing := range a.runtimeEdits {
+		if existing == key || strings.HasPrefix(existing, key+".") {
+			delete(a.runtimeEdits, existing)
+		}
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && v.Len() > 0 {
+		doc := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			doc[iter.Key().String()] = iter.Value().Interface()
+		}
+		for subKey, subValue := range internal.FlattenSettings(doc) {
+			a.runtimeEdits[key+"."+subKey] = subValue
+		}
+		return
+	}
+
+	a.runtimeEdits[key] = value
+}
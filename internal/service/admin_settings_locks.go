@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal/db"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSettingLocked       = errors.New("setting is locked")
+	ErrSettingLockNotFound = errors.New("setting lock not found")
+)
+
+// SettingLockedError is returned when a change touches a locked setting.
+type SettingLockedError struct {
+	Lock *messages.SettingLock
+}
+
+func (e *SettingLockedError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrSettingLocked, e.Lock.Key, e.Lock.Reason)
+}
+
+func (e *SettingLockedError) Is(target error) bool {
+	return target == ErrSettingLocked
+}
+
+// LockSetting freezes the key, or every setting under it, until it is unlocked or expiresAt passes. Locking
+// an already locked key replaces its reason and expiry. Locks apply to every admin, whatever their permissions.
+func (a *AdminSettingsService) LockSetting(actor uint, key string, reason string, expiresAt *time.Time) (*messages.SettingLock, error) {
+	key = normalizePrefix(key)
+	if key == "" || !a.ctx.Config().Exists(key) {
+		return nil, ErrSettingNotFound
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("a reason is required to lock a setting")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+	if !a.CanWrite(actor, key) {
+		return nil, ErrPermissionDenied
+	}
+
+	var lock models.SettingsLock
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where(&models.SettingsLock{Key: key}).FirstOrInit(&lock)
+	}); err != nil {
+		return nil, err
+	}
+
+	lock.Reason = reason
+	lock.CreatedBy = actor
+	lock.ExpiresAt = expiresAt
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Save(&lock)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("setting locked",
+		zap.String("key", key),
+		zap.String("reason", reason),
+		zap.Timep("expires_at", expiresAt),
+		zap.Uint("user_id", actor),
+	)
+
+	return settingLock(&lock), nil
+}
+
+// UnlockSetting removes the lock on the key. The key must match the locked key or prefix exactly.
+func (a *AdminSettingsService) UnlockSetting(actor uint, key string) error {
+	key = normalizePrefix(key)
+	if !a.CanWrite(actor, key) {
+		return ErrPermissionDenied
+	}
+
+	var deleted int64
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		tx := db.Unscoped().Where(&models.SettingsLock{Key: key}).Delete(&models.SettingsLock{})
+		deleted = tx.RowsAffected
+		return tx
+	}); err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrSettingLockNotFound
+	}
+
+	a.ctx.Logger().Info("setting unlocked",
+		zap.String("key", key),
+		zap.Uint("user_id", actor),
+	)
+
+	return nil
+}
+
+// ListSettingLocks returns the locks that have not expired.
+func (a *AdminSettingsService) ListSettingLocks() ([]*messages.SettingLock, error) {
+	locks, err := a.activeLocks()
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*messages.SettingLock, len(locks))
+	for i := range locks {
+		response[i] = settingLock(&locks[i])
+	}
+
+	return response, nil
+}
+
+func (a *AdminSettingsService) activeLocks() ([]models.SettingsLock, error) {
+	var locks []models.SettingsLock
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Order("created_at ASC").Find(&locks)
+	}); err != nil {
+		return nil, err
+	}
+
+	return locks, nil
+}
+
+// checkSettingLock returns a SettingLockedError if the key is under a lock or holds a locked key.
+func (a *AdminSettingsService) checkSettingLock(key string) error {
+	locks, err := a.activeLocks()
+	if err != nil {
+		return err
+	}
+
+	if lock := findSettingLock(locks, key, true); lock != nil {
+		return &SettingLockedError{Lock: settingLock(lock)}
+	}
+
+	return nil
+}
+
+// applyLock fills in the lock covering the setting, if any.
+func (a *AdminSettingsService) applyLock(setting *messages.SettingsItem, locks []models.SettingsLock) {
+	if lock := findSettingLock(locks, setting.Key, false); lock != nil {
+		setting.Lock = settingLock(lock)
+	}
+}
+
+// findSettingLock returns the lock covering the key. With nested set, locks on keys inside the setting
+// count as well, since replacing the setting would change them.
+func findSettingLock(locks []models.SettingsLock, key string, nested bool) *models.SettingsLock {
+	for i, lock := range locks {
+		if key == lock.Key || strings.HasPrefix(key, lock.Key+".") {
+			return &locks[i]
+		}
+		if nested && strings.HasPrefix(lock.Key, key+".") {
+			return &locks[i]
+		}
+	}
+	return nil
+}
+
+func settingLock(lock *models.SettingsLock) *messages.SettingLock {
+	return &messages.SettingLock{
+		Key:       lock.Key,
+		Reason:    lock.Reason,
+		LockedBy:  lock.CreatedBy,
+		LockedAt:  lock.UpdatedAt,
+		ExpiresAt: lock.ExpiresAt,
+	}
+}
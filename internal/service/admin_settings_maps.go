@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrSettingNotMap    = errors.New("setting is not a map")
+	ErrMapEntryNotFound = errors.New("map entry not found")
+)
+
+// SetMapEntry adds or replaces the entry of a map setting named by the last segment of the dotted key,
+// and reports whether the entry was created. The value is checked against the map's additionalProperties
+// schema and converted to the map's value type. A non-empty version must match the version of the map, and
+// the change fails with ErrVersionMismatch if the map changes before it is applied.
+func (a *AdminSettingsService) SetMapEntry(actor uint, key string, value any, version string) (bool, error) {
+	mapKey, name, current, err := a.mapEntryParent(key, version)
+	if err != nil {
+		return false, err
+	}
+
+	entryKey, err := internal.MapKey(name, current.Type().Key())
+	if err != nil {
+		return false, err
+	}
+
+	if mapSchema := a.settingSchema(mapKey); mapSchema != nil {
+		if err := mapSchema.PropertyNames.Validate(name); err != nil {
+			return false, err
+		}
+	}
+	if entrySchema := a.settingSchema(key); entrySchema != nil {
+		if err := entrySchema.Validate(value); err != nil {
+			return false, err
+		}
+	}
+
+	existing := current.MapIndex(entryKey)
+	created := !existing.IsValid()
+
+	currentEntry := reflect.Zero(current.Type().Elem()).Interface()
+	if !created {
+		currentEntry = existing.Interface()
+	}
+
+	normalized, err := a.NormalizeSetting(key, currentEntry, a.restoreRedacted(key, currentEntry, value))
+	if err != nil {
+		return false, err
+	}
+
+	entryValue := reflect.ValueOf(normalized)
+	if !entryValue.IsValid() {
+		entryValue = reflect.Zero(current.Type().Elem())
+	}
+
+	updated := copyMap(current)
+	updated.SetMapIndex(entryKey, entryValue)
+
+	_, err = a.updateSetting(a.AccessFor(actor), &messages.SettingsItem{Key: mapKey, Value: updated.Interface(), Version: SettingVersion(current.Interface())})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// DeleteMapEntry removes the entry of a map setting named by the last segment of the dotted key.
+// A non-empty version must match the version of the map.
+func (a *AdminSettingsService) DeleteMapEntry(actor uint, key string, version string) error {
+	mapKey, name, current, err := a.mapEntryParent(key, version)
+	if err != nil {
+		return err
+	}
+
+	entryKey, err := internal.MapKey(name, current.Type().Key())
+	if err != nil {
+		return ErrMapEntryNotFound
+	}
+	if !current.MapIndex(entryKey).IsValid() {
+		return ErrMapEntryNotFound
+	}
+
+	updated := copyMap(current)
+	updated.SetMapIndex(entryKey, reflect.Value{})
+
+	_, err = a.updateSetting(a.AccessFor(actor), &messages.SettingsItem{Key: mapKey, Value: updated.Interface(), Version: SettingVersion(current.Interface())})
+	return err
+}
+
+// mapEntryParent splits an entry key into the key of the map setting holding it and the entry name, and
+// returns the current map, which must match a non-empty version.
+func (a *AdminSettingsService) mapEntryParent(key string, version string) (string, string, reflect.Value, error) {
+	index := strings.LastIndex(key, ".")
+	if index <= 0 || index == len(key)-1 {
+		return "", "", reflect.Value{}, ErrSettingNotMap
+	}
+	mapKey, name := key[:index], key[index+1:]
+
+	baseKey := a.settingBaseKey(mapKey)
+	if !a.ctx.Config().Exists(baseKey) {
+		return "", "", reflect.Value{}, ErrSettingNotFound
+	}
+	if !a.ctx.Config().IsEditable(baseKey) {
+		return "", "", reflect.Value{}, ErrSettingNotEditable
+	}
+
+	value, ok := a.settingValue(mapKey)
+	if !ok {
+		return "", "", reflect.Value{}, ErrSettingNotFound
+	}
+
+	current := reflect.ValueOf(value)
+	if current.Kind() != reflect.Map {
+		return "", "", reflect.Value{}, ErrSettingNotMap
+	}
+	if !VersionMatches(version, SettingVersion(value)) {
+		return "", "", reflect.Value{}, ErrVersionMismatch
+	}
+
+	return mapKey, name, current, nil
+}
+
+func copyMap(v reflect.Value) reflect.Value {
+	copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		copied.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return copied
+}
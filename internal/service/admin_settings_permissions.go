@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"go.lumeweb.com/portal/db"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// ACCESS_OPERATOR_ROLE is the access role that may call the read-only settings and cron routes without
+// being an admin. It is granted through the portal's access service like the core roles. Operators should
+// also get the operator settings policy, so responses show them every setting as read only.
+const ACCESS_OPERATOR_ROLE = "operator"
+
+// Settings policies scope what an actor may change. Whether they can reach the settings routes at all is
+// decided by their access role.
+const (
+	// SettingsRoleOperator cannot change any setting.
+	SettingsRoleOperator = "operator"
+	// SettingsRoleEditor can change settings under its write prefixes.
+	SettingsRoleEditor = "editor"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// GetPermission returns the settings policy for the user, or nil if the user has full access.
+func (a *AdminSettingsService) GetPermission(userID uint) (*models.SettingsPermission, error) {
+	var permission models.SettingsPermission
+
+	err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where(&models.SettingsPermission{UserID: userID}).First(&permission)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &permission, nil
+}
+
+func (a *AdminSettingsService) ListPermissions() ([]models.SettingsPermission, error) {
+	var permissions []models.SettingsPermission
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Order("user_id ASC").Find(&permissions)
+	}); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// SetPermission creates or replaces the settings policy for a user.
+func (a *AdminSettingsService) SetPermission(userID uint, role string, writePrefixes []string) (*models.SettingsPermission, error) {
+	if role != SettingsRoleOperator && role != SettingsRoleEditor {
+		return nil, fmt.Errorf("unknown settings role: %s", role)
+	}
+	if role == SettingsRoleOperator && len(writePrefixes) > 0 {
+		return nil, fmt.Errorf("the %s role cannot have write prefixes", SettingsRoleOperator)
+	}
+
+	prefixes := make([]string, 0, len(writePrefixes))
+	for _, prefix := range writePrefixes {
+		if prefix = normalizePrefix(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	permission, err := a.GetPermission(userID)
+	if err != nil {
+		return nil, err
+	}
+	if permission == nil {
+		permission = &models.SettingsPermission{UserID: userID}
+	}
+
+	permission.Role = role
+	permission.WritePrefixes = prefixes
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Save(permission)
+	}); err != nil {
+		return nil, err
+	}
+
+	return permission, nil
+}
+
+// DeletePermission removes the user's policy, restoring full access.
+func (a *AdminSettingsService) DeletePermission(userID uint) error {
+	return db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where(&models.SettingsPermission{UserID: userID}).Delete(&models.SettingsPermission{})
+	})
+}
+
+// SettingsAccess is the settings policy of an actor, loaded once so a request can check many keys
+// against it.
+type SettingsAccess struct {
+	actor      uint
+	permission *models.SettingsPermission
+	err        error
+}
+
+// AccessFor loads the settings policy of the actor. The system actor (0) has full access.
+func (a *AdminSettingsService) AccessFor(actor uint) *SettingsAccess {
+	access := &SettingsAccess{actor: actor}
+	if actor != 0 {
+		access.permission, access.err = a.GetPermission(actor)
+	}
+	return access
+}
+
+// Actor returns the user ID the policy belongs to, or 0 for the system.
+func (s *SettingsAccess) Actor() uint {
+	return s.actor
+}
+
+// FullAccess reports whether the actor has no settings policy restricting them. Lookup failures deny access.
+func (s *SettingsAccess) FullAccess() bool {
+	return s.err == nil && s.permission == nil
+}
+
+// CanWrite reports whether the actor may change the setting. Lookup failures deny access.
+func (s *SettingsAccess) CanWrite(key string) bool {
+	return s.err == nil && permissionAllows(s.permission, key)
+}
+
+// HasFullAccess reports whether the user has no settings policy restricting them.
+func (a *AdminSettingsService) HasFullAccess(userID uint) bool {
+	return a.AccessFor(userID).FullAccess()
+}
+
+// CanWrite reports whether the actor may change the setting. The system actor (0) may change anything,
+// and lookup failures deny access. Callers checking several keys should load the policy once with AccessFor.
+func (a *AdminSettingsService) CanWrite(actor uint, key string) bool {
+	return a.AccessFor(actor).CanWrite(key)
+}
+
+// ApplyPermissions clears the editable flag of settings the actor may not change.
+func (a *AdminSettingsService) ApplyPermissions(access *SettingsAccess, settings ...*messages.SettingsItem) {
+	for _, setting := range settings {
+		if !access.CanWrite(setting.Key) {
+			setting.Editable = false
+		}
+	}
+}
+
+// GetSchemaFor returns the config schema with properties the actor may not change marked read only.
+func (a *AdminSettingsService) GetSchemaFor(access *SettingsAccess) *schema.Schema {
+	root := a.GetSchema()
+	if access.FullAccess() {
+		return root
+	}
+
+	restricted := root.Clone()
+	markReadOnly(restricted, "", access.CanWrite)
+
+	return restricted
+}
+
+func markReadOnly(_schema *schema.Schema, path string, writable func(string) bool) {
+	if _schema == nil {
+		return
+	}
+
+	if _schema.Properties != nil && _schema.Properties.Len() > 0 {
+		for pair := _schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			markReadOnly(pair.Value, buildFullPath(path, pair.Key), writable)
+		}
+		return
+	}
+
+	if path != "" && !writable(path) {
+		_schema.ReadOnly = true
+	}
+}
+
+func permissionAllows(permission *models.SettingsPermission, key string) bool {
+	if permission == nil {
+		return true
+	}
+
+	if permission.Role != SettingsRoleEditor {
+		return false
+	}
+
+	for _, prefix := range permission.WritePrefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizePrefix accepts both "core.mail" and "core.mail.*" forms.
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	prefix = strings.TrimSuffix(prefix, "*")
+	return strings.TrimSuffix(prefix, ".")
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-sql-driver/mysql"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal/config"
+	"go.uber.org/zap"
+	"net"
+	"net/smtp"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SettingsProbeMail     = "mail"
+	SettingsProbeStorage  = "storage"
+	SettingsProbeDatabase = "database"
+)
+
+const (
+	DefaultSettingsProbeTimeout = 10 * time.Second
+	MaxSettingsProbeTimeout     = time.Minute
+)
+
+var ErrUnknownSettingsProbe = errors.New("unknown settings probe")
+
+// settingsProbe checks that a group of settings works against the live service it points at. A group is
+// the portal config struct the probe reads, so its keys follow wherever that struct sits in the config.
+// The target keys name where the probe connects to, so stored secrets are only sent back to the same place.
+type settingsProbe struct {
+	config reflect.Type
+	target []string
+	run    func(ctx context.Context, cfg any, steps *probeSteps)
+}
+
+func newSettingsProbe[T any](run func(ctx context.Context, cfg *T, steps *probeSteps), target ...string) settingsProbe {
+	return settingsProbe{
+		config: reflect.TypeFor[T](),
+		target: target,
+		run: func(ctx context.Context, cfg any, steps *probeSteps) {
+			run(ctx, cfg.(*T), steps)
+		},
+	}
+}
+
+var settingsProbes = map[string]settingsProbe{
+	SettingsProbeMail:     newSettingsProbe(probeMail, "host", "port"),
+	SettingsProbeStorage:  newSettingsProbe(probeStorage, "endpoint", "region"),
+	SettingsProbeDatabase: newSettingsProbe(probeDatabase, "type", "host", "port", "file"),
+}
+
+// ProbeSettings runs the connectivity check of the group with the proposed values laid over the running
+// config, without changing anything. Proposed keys are relative to the group, e.g. "host" for mail. Stored
+// secrets, whether masked or left out, are only used while the probe connects to the same target as the
+// running config; otherwise they must be sent in full. The actor must be allowed to change the group, and
+// probes using stored secrets are logged like a reveal.
+func (a *AdminSettingsService) ProbeSettings(actor uint, group string, proposed map[string]any, timeout time.Duration) (*messages.SettingsProbeResponse, error) {
+	probe, ok := settingsProbes[group]
+	if !ok {
+		return nil, ErrUnknownSettingsProbe
+	}
+
+	prefix, current, ok := findConfigStruct(reflect.ValueOf(a.ctx.Config().Config()), probe.config)
+	if !ok {
+		return nil, ErrUnknownSettingsProbe
+	}
+
+	if !a.CanWrite(actor, prefix) {
+		return nil, ErrPermissionDenied
+	}
+
+	cfg, storedSecrets, err := a.probeConfig(probe, prefix, current, proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(storedSecrets) > 0 {
+		a.ctx.Logger().Info("stored secrets used by settings probe",
+			zap.String("group", group),
+			zap.Strings("keys", storedSecrets),
+			zap.Uint("user_id", actor),
+		)
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultSettingsProbeTimeout
+	}
+	timeout = min(timeout, MaxSettingsProbeTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	steps := &probeSteps{}
+	start := time.Now()
+	probe.run(ctx, cfg, steps)
+
+	return &messages.SettingsProbeResponse{
+		Group:    group,
+		Success:  steps.success(),
+		Duration: time.Since(start).Milliseconds(),
+		Steps:    steps.steps,
+	}, nil
+}
+
+// probeConfig lays the proposed values over a copy of the group's config struct and returns a pointer to it,
+// along with the keys of the stored secrets it kept. Stored secrets are refused when the target changes.
+func (a *AdminSettingsService) probeConfig(probe settingsProbe, prefix string, current reflect.Value, proposed map[string]any) (any, []string, error) {
+	provided := make(map[string]bool)
+
+	value := current.Interface()
+	for name, proposedValue := range internal.FlattenSettings(proposed) {
+		key := prefix + "." + name
+		if proposedValue == RedactedValue && a.IsSensitive(key) {
+			continue
+		}
+		provided[name] = true
+
+		updated, err := internal.SetPath(value, strings.Split(name, "."), func(fieldValue any) (any, error) {
+			return a.NormalizeSetting(key, fieldValue, proposedValue)
+		})
+		if err != nil {
+			return nil, nil, &SettingValidationError{Key: key, Errors: []string{err.Error()}}
+		}
+		value = updated
+	}
+
+	var storedSecrets []string
+	if stored, ok := internal.EncodeSetting(current.Interface()).(map[string]any); ok {
+		for name, secret := range internal.FlattenSettings(stored) {
+			if !provided[name] && secret != nil && !reflect.ValueOf(secret).IsZero() && a.IsSensitive(prefix+"."+name) {
+				storedSecrets = append(storedSecrets, prefix+"."+name)
+			}
+		}
+	}
+	sort.Strings(storedSecrets)
+
+	if len(storedSecrets) > 0 && probeTargetChanged(probe.target, current.Interface(), value) {
+		errs := make([]string, 0, len(storedSecrets))
+		for _, key := range storedSecrets {
+			errs = append(errs, fmt.Sprintf("%s must be provided when probing a different target", key))
+		}
+		return nil, nil, &SettingValidationError{Key: prefix, Errors: errs}
+	}
+
+	cfg := reflect.New(current.Type())
+	cfg.Elem().Set(reflect.ValueOf(value))
+	return cfg.Interface(), storedSecrets, nil
+}
+
+// probeTargetChanged reports whether any of the target keys differs between the running and probed config.
+func probeTargetChanged(target []string, current, probed any) bool {
+	for _, name := range target {
+		path := strings.Split(name, ".")
+		currentValue, _ := internal.LookupPath(current, path)
+		probedValue, _ := internal.LookupPath(probed, path)
+		if !reflect.DeepEqual(currentValue, probedValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// findConfigStruct returns the key and value of the shallowest struct of the given type in the config.
+func findConfigStruct(root reflect.Value, t reflect.Type) (string, reflect.Value, bool) {
+	type candidate struct {
+		key   string
+		value reflect.Value
+	}
+
+	queue := []candidate{{value: root}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		v := next.value
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		if v.Type() == t && next.key != "" {
+			return next.key, v, true
+		}
+
+		fields := internal.StructFields(v.Type())
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			queue = append(queue, candidate{key: buildFullPath(next.key, name), value: v.FieldByIndex(fields[name])})
+		}
+	}
+
+	return "", reflect.Value{}, false
+}
+
+// probeSteps records the outcome of each stage of a probe.
+type probeSteps struct {
+	steps []*messages.SettingsProbeStep
+}
+
+// run times the stage and records its result, reporting whether it succeeded.
+func (p *probeSteps) run(name string, stage func() error) bool {
+	start := time.Now()
+	err := stage()
+
+	step := &messages.SettingsProbeStep{
+		Name:     name,
+		Success:  err == nil,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		step.Message = err.Error()
+	}
+
+	p.steps = append(p.steps, step)
+	return err == nil
+}
+
+func (p *probeSteps) success() bool {
+	for _, step := range p.steps {
+		if !step.Success {
+			return false
+		}
+	}
+	return len(p.steps) > 0
+}
+
+// probeMail connects to the SMTP server, greets it, upgrades to TLS when offered and logs in if
+// credentials are configured.
+func probeMail(ctx context.Context, cfg *config.MailConfig, steps *probeSteps) {
+	host := cfg.Host
+	addr := net.JoinHostPort(host, fmt.Sprint(cfg.Port))
+	ssl := cfg.SSL
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var conn net.Conn
+	if !steps.run("connect", func() error {
+		var err error
+		if ssl {
+			conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+		} else {
+			conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		}
+		return err
+	}) {
+		return
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var client *smtp.Client
+	if !steps.run("handshake", func() error {
+		var err error
+		client, err = smtp.NewClient(conn, host)
+		if err != nil {
+			return err
+		}
+		return client.Hello("localhost")
+	}) {
+		return
+	}
+	defer client.Close()
+
+	if !ssl {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if !steps.run("starttls", func() error {
+				return client.StartTLS(tlsConfig)
+			}) {
+				return
+			}
+		}
+	}
+
+	if cfg.Username != "" {
+		if !steps.run("auth", func() error {
+			return client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host))
+		}) {
+			return
+		}
+	}
+
+	_ = client.Quit()
+}
+
+// probeStorage sends a HEAD request for the S3 bucket with the configured credentials.
+func probeStorage(ctx context.Context, cfg *config.S3Config, steps *probeSteps) {
+	bucket := cfg.BufferBucket
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	accessKey := cfg.AccessKey
+	secretKey := cfg.SecretKey
+
+	options := s3.Options{
+		Region:           region,
+		UsePathStyle:     true,
+		RetryMaxAttempts: 1,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}, nil
+		}),
+	}
+
+	if endpoint := cfg.Endpoint; endpoint != "" {
+		if !steps.run("endpoint", func() error {
+			_, err := url.ParseRequestURI(endpoint)
+			return err
+		}) {
+			return
+		}
+		options.BaseEndpoint = aws.String(endpoint)
+	}
+
+	client := s3.New(options)
+
+	steps.run("head_bucket", func() error {
+		if bucket == "" {
+			return errors.New("no bucket configured")
+		}
+		_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+		return err
+	})
+}
+
+// probeDatabase pings a MySQL server, or checks that a SQLite database file is accessible.
+func probeDatabase(ctx context.Context, cfg *config.DatabaseConfig, steps *probeSteps) {
+	switch dbType := cfg.Type; dbType {
+	case "mysql":
+		mysqlConfig := mysql.NewConfig()
+		mysqlConfig.Net = "tcp"
+		mysqlConfig.Addr = net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port))
+		mysqlConfig.User = cfg.Username
+		mysqlConfig.Passwd = cfg.Password
+		mysqlConfig.DBName = cfg.Name
+		if deadline, ok := ctx.Deadline(); ok {
+			mysqlConfig.Timeout = time.Until(deadline)
+		}
+
+		db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+		if !steps.run("open", func() error { return err }) {
+			return
+		}
+		defer db.Close()
+
+		steps.run("ping", func() error {
+			return db.PingContext(ctx)
+		})
+	case "sqlite":
+		steps.run("open", func() error {
+			file, err := os.OpenFile(cfg.File, os.O_RDWR, 0)
+			if err != nil {
+				return err
+			}
+			return file.Close()
+		})
+	default:
+		steps.run("open", func() error {
+			return fmt.Errorf("unsupported database type: %q", dbType)
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"go.lumeweb.com/portal/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runProbe[T any](run func(context.Context, *T, *probeSteps), cfg *T) *probeSteps {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	steps := &probeSteps{}
+	run(ctx, cfg, steps)
+	return steps
+}
+
+// checkSteps compares the names of the recorded steps and whether the last one succeeded.
+func checkSteps(t *testing.T, steps *probeSteps, wantSteps []string, wantSuccess bool) {
+	t.Helper()
+
+	names := make([]string, 0, len(steps.steps))
+	for _, step := range steps.steps {
+		names = append(names, step.Name)
+	}
+	if !reflect.DeepEqual(names, wantSteps) {
+		t.Fatalf("steps = %v, want %v", names, wantSteps)
+	}
+
+	if steps.success() != wantSuccess {
+		last := steps.steps[len(steps.steps)-1]
+		t.Fatalf("success = %v, want %v (last step %s: %s)", steps.success(), wantSuccess, last.Name, last.Message)
+	}
+}
+
+// closedPort returns a local port nothing listens on.
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	return port
+}
+
+// fakeSMTP serves a minimal SMTP dialog without STARTTLS, accepting PLAIN auth only for the password.
+func fakeSMTP(t *testing.T, password string) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, password)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func serveSMTP(conn net.Conn, password string) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		for _, line := range lines {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.Fields(line)[0])
+		switch command {
+		case "EHLO":
+			reply("250-localhost", "250 AUTH PLAIN")
+		case "AUTH":
+			if strings.Contains(line, encodePlainAuth("user", password)) {
+				reply("235 2.7.0 Authentication successful")
+			} else {
+				reply("535 5.7.8 Authentication failed")
+			}
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func encodePlainAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+}
+
+func TestProbeMail(t *testing.T) {
+	port := fakeSMTP(t, "secret")
+
+	tests := []struct {
+		name        string
+		cfg         *config.MailConfig
+		wantSteps   []string
+		wantSuccess bool
+	}{
+		{
+			name:        "no credentials",
+			cfg:         &config.MailConfig{Host: "127.0.0.1", Port: port},
+			wantSteps:   []string{"connect", "handshake"},
+			wantSuccess: true,
+		},
+		{
+			name:        "valid credentials",
+			cfg:         &config.MailConfig{Host: "127.0.0.1", Port: port, Username: "user", Password: "secret"},
+			wantSteps:   []string{"connect", "handshake", "auth"},
+			wantSuccess: true,
+		},
+		{
+			name:        "invalid credentials",
+			cfg:         &config.MailConfig{Host: "127.0.0.1", Port: port, Username: "user", Password: "wrong"},
+			wantSteps:   []string{"connect", "handshake", "auth"},
+			wantSuccess: false,
+		},
+		{
+			name:        "connection refused",
+			cfg:         &config.MailConfig{Host: "127.0.0.1", Port: closedPort(t)},
+			wantSteps:   []string{"connect"},
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSteps(t, runProbe(probeMail, tt.cfg), tt.wantSteps, tt.wantSuccess)
+		})
+	}
+}
+
+func TestProbeStorage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/uploads" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name        string
+		cfg         *config.S3Config
+		wantSteps   []string
+		wantSuccess bool
+	}{
+		{
+			name:        "bucket exists",
+			cfg:         &config.S3Config{BufferBucket: "uploads", Endpoint: server.URL, AccessKey: "key", SecretKey: "secret"},
+			wantSteps:   []string{"endpoint", "head_bucket"},
+			wantSuccess: true,
+		},
+		{
+			name:        "bucket missing",
+			cfg:         &config.S3Config{BufferBucket: "missing", Endpoint: server.URL, AccessKey: "key", SecretKey: "secret"},
+			wantSteps:   []string{"endpoint", "head_bucket"},
+			wantSuccess: false,
+		},
+		{
+			name:        "no bucket",
+			cfg:         &config.S3Config{Endpoint: server.URL},
+			wantSteps:   []string{"endpoint", "head_bucket"},
+			wantSuccess: false,
+		},
+		{
+			name:        "invalid endpoint",
+			cfg:         &config.S3Config{BufferBucket: "uploads", Endpoint: "not a url"},
+			wantSteps:   []string{"endpoint"},
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSteps(t, runProbe(probeStorage, tt.cfg), tt.wantSteps, tt.wantSuccess)
+		})
+	}
+}
+
+func TestProbeDatabase(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "portal.db")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.DatabaseConfig
+		wantSteps   []string
+		wantSuccess bool
+	}{
+		{
+			name:        "sqlite file",
+			cfg:         &config.DatabaseConfig{Type: "sqlite", File: file},
+			wantSteps:   []string{"open"},
+			wantSuccess: true,
+		},
+		{
+			name:        "sqlite missing file",
+			cfg:         &config.DatabaseConfig{Type: "sqlite", File: filepath.Join(t.TempDir(), "missing.db")},
+			wantSteps:   []string{"open"},
+			wantSuccess: false,
+		},
+		{
+			name:        "mysql unreachable",
+			cfg:         &config.DatabaseConfig{Type: "mysql", Host: "127.0.0.1", Port: closedPort(t), Name: "portal"},
+			wantSteps:   []string{"open", "ping"},
+			wantSuccess: false,
+		},
+		{
+			name:        "unsupported type",
+			cfg:         &config.DatabaseConfig{Type: "postgres"},
+			wantSteps:   []string{"open"},
+			wantSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSteps(t, runProbe(probeDatabase, tt.cfg), tt.wantSteps, tt.wantSuccess)
+		})
+	}
+}
+
+type probeTestMail struct {
+	Host string `config:"host"`
+}
+
+type probeTestCore struct {
+	Backup *probeTestMail `config:"backup"`
+	Mail   probeTestMail  `config:"mail"`
+}
+
+type probeTestConfig struct {
+	Core    probeTestCore `config:"core"`
+	Plugins struct {
+		Mailer struct {
+			Mail probeTestMail `config:"mail"`
+		} `config:"mailer"`
+	} `config:"plugins"`
+}
+
+func TestFindConfigStruct(t *testing.T) {
+	// The nil backup is skipped and the plugin's mail config is nested deeper than the core one
+	cfg := &probeTestConfig{Core: probeTestCore{Mail: probeTestMail{Host: "mail.example.com"}}}
+
+	key, value, ok := findConfigStruct(reflect.ValueOf(cfg), reflect.TypeFor[probeTestMail]())
+	if !ok {
+		t.Fatal("mail config not found")
+	}
+	if key != "core.mail" {
+		t.Fatalf("key = %q, want core.mail", key)
+	}
+	if host := value.Interface().(probeTestMail).Host; host != "mail.example.com" {
+		t.Fatalf("host = %q, want mail.example.com", host)
+	}
+
+	if _, _, ok := findConfigStruct(reflect.ValueOf(cfg), reflect.TypeFor[config.S3Config]()); ok {
+		t.Fatal("found a config struct that is not there")
+	}
+}
+
+func TestProbeStepsSuccess(t *testing.T) {
+	steps := &probeSteps{}
+	if steps.success() {
+		t.Fatal("a probe without steps succeeded")
+	}
+
+	steps.run("first", func() error { return nil })
+	if !steps.success() {
+		t.Fatal("a probe with only passing steps failed")
+	}
+
+	steps.run("second", func() error { return strconv.ErrSyntax })
+	if steps.success() {
+		t.Fatal("a probe with a failing step succeeded")
+	}
+}
+
+func TestProbeConfigStoredSecrets(t *testing.T) {
+	a := newTestSettingsService(nil, nil)
+	probe := settingsProbes[SettingsProbeMail]
+	current := reflect.ValueOf(config.MailConfig{Host: "mail.example.com", Port: 587, Username: "user", Password: "secret"})
+
+	tests := []struct {
+		name         string
+		proposed     map[string]any
+		wantPassword string
+		wantStored   bool
+		wantErr      bool
+	}{
+		{
+			name:         "masked secret, same target",
+			proposed:     map[string]any{"username": "other", "password": RedactedValue},
+			wantPassword: "secret",
+			wantStored:   true,
+		},
+		{
+			name:         "omitted secret, same target",
+			proposed:     map[string]any{"ssl": true},
+			wantPassword: "secret",
+			wantStored:   true,
+		},
+		{
+			name:     "masked secret, new host",
+			proposed: map[string]any{"host": "attacker.example.com", "password": RedactedValue},
+			wantErr:  true,
+		},
+		{
+			name:     "omitted secret, new port",
+			proposed: map[string]any{"port": 2525},
+			wantErr:  true,
+		},
+		{
+			name:         "real secret, new host",
+			proposed:     map[string]any{"host": "smtp.example.net", "password": "other-secret"},
+			wantPassword: "other-secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, stored, err := a.probeConfig(probe, "core.mail", current, tt.proposed)
+			if tt.wantErr {
+				if !errors.Is(err, ErrSettingInvalid) {
+					t.Fatalf("err = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if password := cfg.(*config.MailConfig).Password; password != tt.wantPassword {
+				t.Fatalf("password = %q, want %q", password, tt.wantPassword)
+			}
+			if (len(stored) > 0) != tt.wantStored {
+				t.Fatalf("stored secrets = %v, want used: %v", stored, tt.wantStored)
+			}
+		})
+	}
+}
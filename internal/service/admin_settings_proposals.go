@@ -0,0 +1,458 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal/db"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSettingsProposalTTL = 24 * time.Hour
+	MaxSettingsProposalTTL     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrApprovalRequired    = errors.New("setting change requires an approved proposal")
+	ErrApprovalNotRequired = errors.New("setting is not protected, change it directly")
+	ErrProposalNotPending  = errors.New("proposal is no longer pending")
+	ErrSelfApproval        = errors.New("proposals must be approved by a different admin")
+	ErrProposalOutdated    = errors.New("setting changed after the proposal was made")
+)
+
+// ListProtectedPrefixes returns the prefixes whose settings can only be changed through an approved proposal.
+func (a *AdminSettingsService) ListProtectedPrefixes() ([]string, error) {
+	var protected []models.SettingsProtectedPrefix
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Order("prefix ASC").Find(&protected)
+	}); err != nil {
+		return nil, err
+	}
+
+	prefixes := make([]string, len(protected))
+	for i, prefix := range protected {
+		prefixes[i] = prefix.Prefix
+	}
+
+	return prefixes, nil
+}
+
+// ProtectedPrefixesKey is the proposal key for changing the protected prefixes themselves. Its value is the
+// complete new list of prefixes.
+const ProtectedPrefixesKey = "$protected_prefixes"
+
+// SetProtectedPrefixes replaces the protected prefixes. Only admins with full settings access may change them.
+// Adding prefixes takes effect directly, but removing any needs a second admin, so it fails with
+// ErrApprovalRequired and has to be proposed under ProtectedPrefixesKey instead.
+func (a *AdminSettingsService) SetProtectedPrefixes(actor uint, prefixes []string) ([]string, error) {
+	if actor != 0 && !a.HasFullAccess(actor) {
+		return nil, ErrPermissionDenied
+	}
+
+	normalized := normalizeProtectedPrefixes(prefixes)
+
+	err := a.replaceProtectedPrefixes(actor, normalized, func(current []string) error {
+		if actor != 0 && len(removedPrefixes(current, normalized)) > 0 {
+			return ErrApprovalRequired
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// replaceProtectedPrefixes stores the normalized prefixes once check accepts the current ones. Both happen
+// under protectedMu, so the check sees the list that is replaced.
+func (a *AdminSettingsService) replaceProtectedPrefixes(actor uint, normalized []string, check func(current []string) error) error {
+	a.protectedMu.Lock()
+	defer a.protectedMu.Unlock()
+
+	current, err := a.ListProtectedPrefixes()
+	if err != nil {
+		return err
+	}
+	if err := check(current); err != nil {
+		return err
+	}
+
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("1 = 1").Delete(&models.SettingsProtectedPrefix{}).Error; err != nil {
+			return err
+		}
+		for _, prefix := range normalized {
+			if err := tx.Create(&models.SettingsProtectedPrefix{Prefix: prefix, CreatedBy: actor}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// Drop the cached prefixes even on failure, since the transaction may have committed
+	a.protectedPrefixes = nil
+	if err != nil {
+		return err
+	}
+
+	a.ctx.Logger().Info("protected settings prefixes changed",
+		zap.Strings("prefixes", normalized),
+		zap.Strings("removed", removedPrefixes(current, normalized)),
+		zap.Uint("user_id", actor),
+	)
+
+	return nil
+}
+
+// normalizeProtectedPrefixes cleans up, deduplicates and sorts the prefixes.
+func normalizeProtectedPrefixes(prefixes []string) []string {
+	unique := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix = normalizePrefix(prefix); prefix != "" {
+			unique[prefix] = true
+		}
+	}
+
+	normalized := make([]string, 0, len(unique))
+	for prefix := range unique {
+		normalized = append(normalized, prefix)
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+// removedPrefixes returns the prefixes in current that are missing from updated.
+func removedPrefixes(current, updated []string) []string {
+	kept := make(map[string]bool, len(updated))
+	for _, prefix := range updated {
+		kept[prefix] = true
+	}
+
+	var removed []string
+	for _, prefix := range current {
+		if !kept[prefix] {
+			removed = append(removed, prefix)
+		}
+	}
+	return removed
+}
+
+// protectedPrefixesValue reads a proposed list of protected prefixes.
+func protectedPrefixesValue(value any) ([]string, error) {
+	prefixes, err := internal.NormalizeSetting([]string{}, value)
+	if err != nil {
+		return nil, &SettingValidationError{Key: ProtectedPrefixesKey, Errors: []string{err.Error()}}
+	}
+	return normalizeProtectedPrefixes(prefixes.([]string)), nil
+}
+
+// RequiresApproval reports whether changing the key touches a protected prefix, either because the key
+// is under one or because it holds a protected key.
+func (a *AdminSettingsService) RequiresApproval(key string) bool {
+	prefixes, err := a.cachedProtectedPrefixes()
+	if err != nil {
+		a.ctx.Logger().Error("failed to load protected settings prefixes", zap.Error(err))
+		// Fail closed, an unprotected change cannot be undone
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") || strings.HasPrefix(prefix, key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cachedProtectedPrefixes returns the protected prefixes, loading them on first use and again after
+// SetProtectedPrefixes changes them.
+func (a *AdminSettingsService) cachedProtectedPrefixes() ([]string, error) {
+	a.protectedMu.Lock()
+	defer a.protectedMu.Unlock()
+
+	if a.protectedPrefixes == nil {
+		prefixes, err := a.ListProtectedPrefixes()
+		if err != nil {
+			return nil, err
+		}
+		a.protectedPrefixes = prefixes
+	}
+
+	return a.protectedPrefixes, nil
+}
+
+// ProposeSettingChange validates a change to a protected setting and stores it until another admin approves
+// or rejects it, or it expires after ttl. The proposal only applies to the value the setting has now.
+func (a *AdminSettingsService) ProposeSettingChange(actor uint, key string, value any, reason string, ttl time.Duration) (*models.SettingsProposal, error) {
+	if ttl <= 0 {
+		ttl = DefaultSettingsProposalTTL
+	}
+	if ttl > MaxSettingsProposalTTL {
+		return nil, fmt.Errorf("proposals cannot be open for longer than %s", MaxSettingsProposalTTL)
+	}
+
+	var current any
+	if key == ProtectedPrefixesKey {
+		if !a.HasFullAccess(actor) {
+			return nil, ErrPermissionDenied
+		}
+
+		prefixes, err := protectedPrefixesValue(value)
+		if err != nil {
+			return nil, err
+		}
+		value = prefixes
+
+		if current, err = a.ListProtectedPrefixes(); err != nil {
+			return nil, err
+		}
+	} else {
+		if !a.RequiresApproval(a.settingBaseKey(key)) {
+			return nil, ErrApprovalNotRequired
+		}
+
+		current, _ = a.settingValue(key)
+
+		if _, err := a.ValidateSetting(actor, &messages.SettingsItem{Key: key, Value: value}); err != nil {
+			return nil, err
+		}
+	}
+
+	proposal := &models.SettingsProposal{
+		Key:        key,
+		Value:      storableValue(value),
+		Version:    SettingVersion(current),
+		Reason:     reason,
+		Status:     models.SettingsProposalPending,
+		ProposedBy: actor,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Create(proposal)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("setting change proposed",
+		zap.Uint("id", proposal.ID),
+		zap.String("key", key),
+		zap.Time("expires_at", proposal.ExpiresAt),
+		zap.Uint("user_id", actor),
+	)
+
+	return proposal, nil
+}
+
+// ListSettingsProposals returns proposals newest first, limited to the status if one is given.
+func (a *AdminSettingsService) ListSettingsProposals(status string) ([]models.SettingsProposal, error) {
+	if err := a.expireSettingsProposals(); err != nil {
+		return nil, err
+	}
+
+	var proposals []models.SettingsProposal
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		query := db.Order("created_at DESC")
+		if status != "" {
+			query = query.Where(&models.SettingsProposal{Status: status})
+		}
+		return query.Find(&proposals)
+	}); err != nil {
+		return nil, err
+	}
+
+	return proposals, nil
+}
+
+// GetSettingsProposal returns a single proposal. It returns gorm.ErrRecordNotFound if there is none.
+func (a *AdminSettingsService) GetSettingsProposal(id uint) (*models.SettingsProposal, error) {
+	if err := a.expireSettingsProposals(); err != nil {
+		return nil, err
+	}
+
+	var proposal models.SettingsProposal
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.First(&proposal, id)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &proposal, nil
+}
+
+// ApproveSettingsProposal applies a pending proposal on behalf of the approver, who must be a different admin
+// allowed to change the setting. The approver claims the proposal before applying it, so a concurrent
+// approval or rejection fails with ErrProposalNotPending instead of overwriting the decision. If applying
+// fails, including because the setting changed after the proposal was made, the proposal is marked failed
+// with the error.
+func (a *AdminSettingsService) ApproveSettingsProposal(actor uint, id uint, comment string) (*models.SettingsProposal, error) {
+	proposal, err := a.pendingSettingsProposal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal.ProposedBy == actor {
+		return nil, ErrSelfApproval
+	}
+	if !a.canDecideSettingsProposal(actor, proposal) {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := a.decideSettingsProposal(proposal, actor, comment, models.SettingsProposalPending, models.SettingsProposalApplying); err != nil {
+		return nil, err
+	}
+
+	if proposal.Key == ProtectedPrefixesKey {
+		err = a.applyProtectedPrefixesProposal(actor, proposal)
+	} else {
+		_, err = a.writeSetting(actor, &messages.SettingsItem{Key: proposal.Key, Value: proposal.Value, Version: proposal.Version})
+	}
+	if errors.Is(err, ErrVersionMismatch) {
+		err = ErrProposalOutdated
+	}
+
+	status := models.SettingsProposalApproved
+	if err != nil {
+		status = models.SettingsProposalFailed
+		proposal.Error = err.Error()
+	}
+
+	if err := a.decideSettingsProposal(proposal, actor, comment, models.SettingsProposalApplying, status); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("setting change proposal approved",
+		zap.Uint("id", proposal.ID),
+		zap.String("key", proposal.Key),
+		zap.String("status", proposal.Status),
+		zap.Uint("proposed_by", proposal.ProposedBy),
+		zap.Uint("user_id", actor),
+	)
+
+	return proposal, nil
+}
+
+// RejectSettingsProposal closes a pending proposal without applying it. The proposer may reject their own
+// proposal to withdraw it.
+func (a *AdminSettingsService) RejectSettingsProposal(actor uint, id uint, comment string) (*models.SettingsProposal, error) {
+	proposal, err := a.pendingSettingsProposal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal.ProposedBy != actor && !a.canDecideSettingsProposal(actor, proposal) {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := a.decideSettingsProposal(proposal, actor, comment, models.SettingsProposalPending, models.SettingsProposalRejected); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("setting change proposal rejected",
+		zap.Uint("id", proposal.ID),
+		zap.String("key", proposal.Key),
+		zap.Uint("proposed_by", proposal.ProposedBy),
+		zap.Uint("user_id", actor),
+	)
+
+	return proposal, nil
+}
+
+// canDecideSettingsProposal reports whether the actor may approve or reject the proposal. Changes to the
+// protected prefixes need the same full access as setting them.
+func (a *AdminSettingsService) canDecideSettingsProposal(actor uint, proposal *models.SettingsProposal) bool {
+	if proposal.Key == ProtectedPrefixesKey {
+		return a.HasFullAccess(actor)
+	}
+	return a.CanWrite(actor, a.settingBaseKey(proposal.Key))
+}
+
+// applyProtectedPrefixesProposal replaces the protected prefixes with the proposed list, as long as they
+// have not changed since the proposal was made.
+func (a *AdminSettingsService) applyProtectedPrefixesProposal(actor uint, proposal *models.SettingsProposal) error {
+	prefixes, err := protectedPrefixesValue(proposal.Value)
+	if err != nil {
+		return err
+	}
+
+	return a.replaceProtectedPrefixes(actor, prefixes, func(current []string) error {
+		if SettingVersion(current) != proposal.Version {
+			return ErrVersionMismatch
+		}
+		return nil
+	})
+}
+
+func (a *AdminSettingsService) pendingSettingsProposal(id uint) (*models.SettingsProposal, error) {
+	proposal, err := a.GetSettingsProposal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal.Status != models.SettingsProposalPending {
+		return nil, ErrProposalNotPending
+	}
+
+	return proposal, nil
+}
+
+// decideSettingsProposal moves the proposal from one status to another and records the decision. The update
+// only matches while the proposal still has the from status, so of two admins deciding at once only one
+// succeeds and the other gets ErrProposalNotPending. Pending proposals past their expiry cannot be decided.
+func (a *AdminSettingsService) decideSettingsProposal(proposal *models.SettingsProposal, actor uint, comment string, from, to string) error {
+	now := time.Now()
+
+	var affected int64
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		query := db.Model(&models.SettingsProposal{}).Where("id = ? AND status = ?", proposal.ID, from)
+		if from == models.SettingsProposalPending {
+			query = query.Where("expires_at > ?", now)
+		} else {
+			query = query.Where("decided_by = ?", actor)
+		}
+
+		result := query.Updates(map[string]any{
+			"status":     to,
+			"decided_by": actor,
+			"decided_at": now,
+			"comment":    comment,
+			"error":      proposal.Error,
+		})
+		affected = result.RowsAffected
+		return result
+	}); err != nil {
+		return err
+	}
+
+	if affected != 1 {
+		return ErrProposalNotPending
+	}
+
+	proposal.Status = to
+	proposal.DecidedBy = &actor
+	proposal.DecidedAt = &now
+	proposal.Comment = comment
+
+	return nil
+}
+
+// expireSettingsProposals marks pending proposals past their expiry as expired.
+func (a *AdminSettingsService) expireSettingsProposals() error {
+	return db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.SettingsProposal{}).
+			Where("status = ? AND expires_at <= ?", models.SettingsProposalPending, time.Now()).
+			Update("status", models.SettingsProposalExpired)
+	})
+}
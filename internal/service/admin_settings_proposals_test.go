@@ -0,0 +1,35 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProtectedPrefixesValue(t *testing.T) {
+	// Proposal values come back from JSON storage as lists of any
+	prefixes, err := protectedPrefixesValue([]any{"core.db.*", " core.mail ", "core.db", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"core.db", "core.mail"}; !reflect.DeepEqual(prefixes, want) {
+		t.Fatalf("prefixes = %v, want %v", prefixes, want)
+	}
+
+	if _, err := protectedPrefixesValue("core.db"); !errors.Is(err, ErrSettingInvalid) {
+		t.Fatalf("err = %v, want a validation error", err)
+	}
+}
+
+func TestRemovedPrefixes(t *testing.T) {
+	current := []string{"core.db", "core.mail", "core.storage"}
+
+	if removed := removedPrefixes(current, []string{"core.db", "core.mail", "core.storage", "core.port"}); len(removed) != 0 {
+		t.Fatalf("adding a prefix removed %v", removed)
+	}
+
+	removed := removedPrefixes(current, []string{"core.mail", "core.port"})
+	if want := []string{"core.db", "core.storage"}; !reflect.DeepEqual(removed, want) {
+		t.Fatalf("removed = %v, want %v", removed, want)
+	}
+}
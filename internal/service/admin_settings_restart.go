@@ -0,0 +1,114 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// restartTag marks a config field that is only read at startup, e.g. `restart:"true"`.
+const restartTag = "restart"
+
+// restartKeys are the config keys, or prefixes of keys, that portal core only reads at startup, such as
+// the database connection and the listening port. They are the fallback for core fields that don't carry
+// the restart tag, so this list has to be kept in step with core.
+var restartKeys = []string{
+	"core.db",
+	"core.domain",
+	"core.port",
+	"core.external_port",
+	"core.identity",
+	"core.clustered",
+	"core.storage",
+}
+
+// restartSchemaExtra is the schema extension keyword flagging restart-required settings.
+const restartSchemaExtra = "x-restart-required"
+
+type pendingRestart struct {
+	bootValue any
+	newValue  any
+	changedAt time.Time
+}
+
+// RequiresRestart reports whether changes to the setting only take effect after a restart, either because
+// it or a parent carries the restart tag or because core lists it in restartKeys.
+func (a *AdminSettingsService) RequiresRestart(key string) bool {
+	parts := strings.Split(key, ".")
+	restart := a.currentSchema().restart
+
+	for i := len(parts); i > 0; i-- {
+		if restart[strings.Join(parts[:i], ".")] {
+			return true
+		}
+	}
+
+	return requiresRestart(key)
+}
+
+func requiresRestart(key string) bool {
+	for _, restartKey := range restartKeys {
+		if key == restartKey || strings.HasPrefix(key, restartKey+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// markRestartRequired flags the schema with restartSchemaExtra.
+func markRestartRequired(_schema *schema.Schema) {
+	if _schema.Extras == nil {
+		_schema.Extras = make(map[string]any)
+	}
+	_schema.Extras[restartSchemaExtra] = true
+}
+
+// GetPendingRestarts lists restart-required settings that have changed since boot.
+func (a *AdminSettingsService) GetPendingRestarts() []*messages.PendingRestartItem {
+	a.pendingMu.RLock()
+	defer a.pendingMu.RUnlock()
+
+	items := make([]*messages.PendingRestartItem, 0, len(a.pendingRestarts))
+	for key, pending := range a.pendingRestarts {
+		items = append(items, &messages.PendingRestartItem{
+			Key:       key,
+			OldValue:  a.RedactValue(key, pending.bootValue),
+			NewValue:  a.RedactValue(key, pending.newValue),
+			ChangedAt: pending.changedAt,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+
+	return items
+}
+
+// trackRestart records a change to a restart-required setting, keeping the value it had at boot.
+// Changing a setting back to its boot value clears the pending entry.
+func (a *AdminSettingsService) trackRestart(key string, oldValue, newValue any) {
+	if !a.RequiresRestart(key) {
+		return
+	}
+
+	a.pendingMu.Lock()
+	defer a.pendingMu.Unlock()
+
+	pending, exists := a.pendingRestarts[key]
+	if !exists {
+		pending = &pendingRestart{bootValue: oldValue}
+	}
+
+	if reflect.DeepEqual(pending.bootValue, newValue) {
+		delete(a.pendingRestarts, key)
+		return
+	}
+
+	pending.newValue = newValue
+	pending.changedAt = time.Now()
+	a.pendingRestarts[key] = pending
+}
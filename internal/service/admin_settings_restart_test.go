@@ -0,0 +1,53 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"reflect"
+	"testing"
+)
+
+type restartTestWorker struct {
+	Threads  int    `config:"threads" restart:"true"`
+	Interval string `config:"interval"`
+}
+
+func TestRequiresRestart(t *testing.T) {
+	builder := &schemaBuilder{
+		schema:     &schema.Schema{Type: "object"},
+		ctx:        newTestContext(nil),
+		sensitive:  make(map[string]bool),
+		restart:    make(map[string]bool),
+		defaults:   make(map[string]any),
+		visiting:   make(map[reflect.Type]bool),
+		structUses: make(map[reflect.Type][]*schema.Schema),
+	}
+	workerSchema := builder.getStructSchema(reflect.TypeFor[restartTestWorker](), "plugins.worker")
+
+	threads, _ := workerSchema.Properties.Get("threads")
+	if threads.Extras[restartSchemaExtra] != true {
+		t.Fatal("tagged field is not flagged in the schema")
+	}
+	interval, _ := workerSchema.Properties.Get("interval")
+	if interval.Extras[restartSchemaExtra] != nil {
+		t.Fatal("untagged field is flagged in the schema")
+	}
+
+	a := newTestSettingsService(nil, &settingsSchema{schema: builder.schema, restart: builder.restart})
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "plugins.worker.threads", want: true},
+		{key: "plugins.worker.interval", want: false},
+		{key: "core.db.host", want: true},
+		{key: "core.port", want: true},
+		{key: "core.portal_name", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := a.RequiresRestart(tt.key); got != tt.want {
+			t.Errorf("RequiresRestart(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
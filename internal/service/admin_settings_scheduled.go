@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+var _ core.Cronable = (*AdminSettingsService)(nil)
+
+const (
+	cronTaskScheduledSettingsName     = "AdminScheduledSettings"
+	cronTaskScheduledSettingsInterval = time.Minute
+)
+
+var ErrScheduledChangeNotCancellable = errors.New("scheduled change can no longer be cancelled")
+
+func (a *AdminSettingsService) RegisterTasks(crn core.CronService) error {
+	crn.RegisterTask(
+		cronTaskScheduledSettingsName,
+		core.CronTaskFuncHandler[*core.CronTaskNoArgs](a.cronTaskScheduledSettings),
+		core.CronTaskDefinitionRecurring(cronTaskScheduledSettingsInterval, false, false),
+		core.CronTaskNoArgsFactory,
+		true,
+	)
+	return nil
+}
+
+func (a *AdminSettingsService) ScheduleJobs(crn core.CronService) error {
+	return crn.CreateJobIfNotExists(cronTaskScheduledSettingsName, nil, nil)
+}
+
+// ScheduleSettingChange validates the change now and stores it to be applied by the cron task at applyAt.
+// A positive revertAfter restores the previous value once that much time has passed after applying.
+func (a *AdminSettingsService) ScheduleSettingChange(actor uint, key string, value any, applyAt time.Time, revertAfter time.Duration) (*models.ScheduledSettingChange, error) {
+	if revertAfter < 0 {
+		return nil, fmt.Errorf("revert_after must not be negative")
+	}
+
+	if _, err := a.ValidateSetting(actor, &messages.SettingsItem{Key: key, Value: value}); err != nil {
+		return nil, err
+	}
+
+	if actor != 0 && a.RequiresApproval(a.settingBaseKey(key)) {
+		return nil, ErrApprovalRequired
+	}
+
+	change := &models.ScheduledSettingChange{
+		Key:         key,
+		Value:       value,
+		ApplyAt:     applyAt,
+		RevertAfter: revertAfter,
+		Status:      models.ScheduledSettingChangePending,
+		CreatedBy:   actor,
+	}
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Create(change)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("setting change scheduled",
+		zap.Uint("id", change.ID),
+		zap.String("key", key),
+		zap.Time("apply_at", applyAt),
+		zap.Duration("revert_after", revertAfter),
+		zap.Uint("user_id", actor),
+	)
+
+	return change, nil
+}
+
+// ListScheduledSettingChanges returns scheduled changes, limited to those with work outstanding unless all is set.
+func (a *AdminSettingsService) ListScheduledSettingChanges(all bool) ([]models.ScheduledSettingChange, error) {
+	var changes []models.ScheduledSettingChange
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		query := db.Order("apply_at ASC")
+		if !all {
+			query = query.Where("status = ? OR (status = ? AND revert_at IS NOT NULL)",
+				models.ScheduledSettingChangePending, models.ScheduledSettingChangeApplied)
+		}
+		return query.Find(&changes)
+	}); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// CancelScheduledSettingChange cancels a pending change, or the pending revert of an applied one, in which
+// case the applied value is kept.
+func (a *AdminSettingsService) CancelScheduledSettingChange(actor uint, id uint) (*models.ScheduledSettingChange, error) {
+	var change models.ScheduledSettingChange
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.First(&change, id)
+	}); err != nil {
+		return nil, err
+	}
+
+	if !a.CanWrite(actor, change.Key) {
+		return nil, ErrPermissionDenied
+	}
+
+	switch {
+	case change.Status == models.ScheduledSettingChangePending:
+		change.Status = models.ScheduledSettingChangeCancelled
+	case change.Status == models.ScheduledSettingChangeApplied && change.RevertAt != nil:
+		change.RevertAt = nil
+	default:
+		return nil, ErrScheduledChangeNotCancellable
+	}
+
+	if err := a.saveScheduledChange(&change); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("scheduled setting change cancelled",
+		zap.Uint("id", change.ID),
+		zap.String("key", change.Key),
+		zap.Uint("user_id", actor),
+	)
+
+	return &change, nil
+}
+
+func (a *AdminSettingsService) cronTaskScheduledSettings(_ *core.CronTaskNoArgs, _ core.Context) error {
+	now := time.Now()
+
+	var due []models.ScheduledSettingChange
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ? AND apply_at <= ?", models.ScheduledSettingChangePending, now).
+			Order("apply_at ASC").Find(&due)
+	}); err != nil {
+		return err
+	}
+
+	for i := range due {
+		a.applyScheduledChange(&due[i])
+	}
+
+	var reverts []models.ScheduledSettingChange
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ? AND revert_at <= ?", models.ScheduledSettingChangeApplied, now).
+			Order("revert_at ASC").Find(&reverts)
+	}); err != nil {
+		return err
+	}
+
+	for i := range reverts {
+		a.revertScheduledChange(&reverts[i])
+	}
+
+	return nil
+}
+
+func (a *AdminSettingsService) applyScheduledChange(change *models.ScheduledSettingChange) {
+	previous, _ := a.settingValue(change.Key)
+
+	// Pin the update to the value recorded as previous, so the revert restores what was actually replaced
+	write, err := a.updateSetting(a.AccessFor(change.CreatedBy), &messages.SettingsItem{
+		Key:     change.Key,
+		Value:   change.Value,
+		Version: SettingVersion(previous),
+	})
+	now := time.Now()
+
+	if err != nil {
+		change.Status = models.ScheduledSettingChangeFailed
+		change.Error = err.Error()
+		a.ctx.Logger().Error("failed to apply scheduled setting change",
+			zap.Uint("id", change.ID),
+			zap.String("key", change.Key),
+			zap.Error(err),
+		)
+	} else {
+		change.Status = models.ScheduledSettingChangeApplied
+		change.PreviousValue = storableValue(previous)
+		change.AppliedVersion = SettingVersion(write.settingValue())
+		change.AppliedAt = &now
+		if change.RevertAfter > 0 {
+			revertAt := now.Add(change.RevertAfter)
+			change.RevertAt = &revertAt
+		}
+		a.ctx.Logger().Info("scheduled setting change applied",
+			zap.Uint("id", change.ID),
+			zap.String("key", change.Key),
+			zap.Uint("user_id", change.CreatedBy),
+		)
+	}
+
+	if err := a.saveScheduledChange(change); err != nil {
+		a.ctx.Logger().Error("failed to save scheduled setting change", zap.Uint("id", change.ID), zap.Error(err))
+	}
+}
+
+// revertScheduledChange restores the previous value, unless the setting was changed again after the scheduled
+// change was applied, in which case the revert fails with ErrVersionMismatch rather than overwrite that edit.
+func (a *AdminSettingsService) revertScheduledChange(change *models.ScheduledSettingChange) {
+	err := a.UpdateSetting(change.CreatedBy, &messages.SettingsItem{
+		Key:     change.Key,
+		Value:   change.PreviousValue,
+		Version: change.AppliedVersion,
+	})
+	now := time.Now()
+
+	if err != nil {
+		change.Status = models.ScheduledSettingChangeFailed
+		change.Error = err.Error()
+		a.ctx.Logger().Error("failed to revert scheduled setting change",
+			zap.Uint("id", change.ID),
+			zap.String("key", change.Key),
+			zap.Error(err),
+		)
+	} else {
+		change.Status = models.ScheduledSettingChangeReverted
+		change.RevertedAt = &now
+		a.ctx.Logger().Info("scheduled setting change reverted",
+			zap.Uint("id", change.ID),
+			zap.String("key", change.Key),
+		)
+	}
+
+	if err := a.saveScheduledChange(change); err != nil {
+		a.ctx.Logger().Error("failed to save scheduled setting change", zap.Uint("id", change.ID), zap.Error(err))
+	}
+}
+
+func (a *AdminSettingsService) saveScheduledChange(change *models.ScheduledSettingChange) error {
+	return db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Save(change)
+	})
+}
+
+// storableValue prepares a value for JSON storage so it normalizes back to the same setting later.
+// Structs are stored under their config names and durations as strings, since a bare number would be
+// read back as seconds.
+func storableValue(value any) any {
+	return internal.EncodeSetting(value)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/json"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type scheduledTestCredentials struct {
+	AccessKey string `config:"access_key"`
+	SecretKey string `config:"secret_key"`
+}
+
+type scheduledTestBackend struct {
+	Name        string                   `config:"name"`
+	Timeout     time.Duration            `config:"timeout"`
+	Credentials scheduledTestCredentials `config:"credentials"`
+}
+
+// TestStorableValueRoundTrip stores values the way previous values of scheduled changes are stored and
+// checks they normalize back to the setting they came from.
+func TestStorableValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "duration", value: 90 * time.Second},
+		{name: "struct", value: scheduledTestBackend{
+			Name:        "primary",
+			Timeout:     5 * time.Second,
+			Credentials: scheduledTestCredentials{AccessKey: "key", SecretKey: "secret"},
+		}},
+		{name: "struct list", value: []scheduledTestBackend{
+			{Name: "primary", Credentials: scheduledTestCredentials{AccessKey: "key"}},
+			{Name: "spare", Timeout: time.Minute},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(storableValue(tt.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stored any
+			if err := json.Unmarshal(data, &stored); err != nil {
+				t.Fatal(err)
+			}
+
+			restored, err := internal.NormalizeSetting(tt.value, stored)
+			if err != nil {
+				t.Fatalf("normalizing %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(restored, tt.value) {
+				t.Fatalf("restored %#v, want %#v", restored, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces sensitive values in responses.
+const RedactedValue = "********"
+
+// sensitiveTag marks a config field as sensitive, e.g. `sensitive:"true"`.
+const sensitiveTag = "sensitive"
+
+// sensitiveWords are matched against the words of a key's last segment.
+var sensitiveWords = []string{"password", "passwd", "secret", "key", "token"}
+
+// IsSensitive reports whether the setting holds secret material, either because a config field
+// along its path is tagged as sensitive or because its name looks like a secret.
+func (a *AdminSettingsService) IsSensitive(key string) bool {
+	parts := strings.Split(key, ".")
+	sensitive := a.currentSchema().sensitive
+
+	for i := len(parts); i > 0; i-- {
+		if sensitive[strings.Join(parts[:i], ".")] {
+			return true
+		}
+	}
+
+	if root := a.GetSchema(); root != nil && schemaWriteOnly(root, key) {
+		return true
+	}
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		if isArrayIndex(parts[i]) {
+			continue
+		}
+		return isSensitiveName(parts[i])
+	}
+
+	return false
+}
+
+// RedactSetting masks the value of a sensitive setting in place, along with any sensitive fields inside
+// list, map and struct values.
+func (a *AdminSettingsService) RedactSetting(setting *messages.SettingsItem) *messages.SettingsItem {
+	if setting.Sensitive {
+		setting.Value = redactValue(setting.Value)
+		return setting
+	}
+	setting.Value = a.redactNested(setting.Key, setting.Value)
+	return setting
+}
+
+// RedactValue masks the value if the key is sensitive, or the fields inside it that are.
+func (a *AdminSettingsService) RedactValue(key string, value any) any {
+	if a.IsSensitive(key) {
+		return redactValue(value)
+	}
+	return a.redactNested(key, value)
+}
+
+// redactNested returns a copy of a list, map or struct value with the fields the schema marks as write
+// only masked. Other values are returned as they are.
+func (a *AdminSettingsService) redactNested(key string, value any) any {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr, reflect.Interface:
+	default:
+		return value
+	}
+
+	root := a.GetSchema()
+	keySchema := schemaForKey(root, key)
+	if keySchema == nil {
+		return value
+	}
+
+	return redactFields(v, keySchema, root).Interface()
+}
+
+func redactFields(v reflect.Value, _schema *schema.Schema, root *schema.Schema) reflect.Value {
+	if !v.IsValid() || _schema == nil {
+		return v
+	}
+	if _schema.WriteOnly {
+		return redactedField(v)
+	}
+
+	// Annotations next to a $ref are kept on the reference itself
+	_schema = _schema.Resolve(root)
+	if _schema == nil {
+		return v
+	}
+	if _schema.WriteOnly {
+		return redactedField(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type().Elem())
+		redacted.Elem().Set(redactFields(v.Elem(), _schema, root))
+		return redacted
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(redactFields(v.Elem(), _schema, root))
+		return redacted
+	case reflect.Slice, reflect.Array:
+		if _schema.Items == nil || (v.Kind() == reflect.Slice && v.IsNil()) {
+			return v
+		}
+		var redacted reflect.Value
+		if v.Kind() == reflect.Slice {
+			redacted = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		} else {
+			redacted = reflect.New(v.Type()).Elem()
+		}
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redactFields(v.Index(i), _schema.Items, root))
+		}
+		return redacted
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value := iter.Value()
+			if valueSchema := propertySchema(_schema, fmt.Sprint(iter.Key().Interface())); valueSchema != nil {
+				value = redactFields(value, valueSchema, root)
+			}
+			redacted.SetMapIndex(iter.Key(), value)
+		}
+		return redacted
+	case reflect.Struct:
+		if _schema.Properties == nil {
+			return v
+		}
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(v)
+		for name, index := range internal.StructFields(v.Type()) {
+			if fieldSchema, ok := _schema.Properties.Get(name); ok {
+				redacted.FieldByIndex(index).Set(redactFields(v.FieldByIndex(index), fieldSchema, root))
+			}
+		}
+		return redacted
+	}
+
+	return v
+}
+
+// redactedField masks a value while keeping its type, so structs holding secrets still encode with the
+// same shape. Only strings and interfaces can hold the mask, other types are cleared.
+func redactedField(v reflect.Value) reflect.Value {
+	if v.IsZero() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(RedactedValue).Convert(v.Type())
+	case reflect.Interface:
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(reflect.ValueOf(RedactedValue))
+		return redacted
+	}
+
+	return reflect.Zero(v.Type())
+}
+
+// restoreRedacted replaces masked values in a value sent back by a client with the current ones, so a
+// setting read with its secrets masked can be saved again without overwriting them.
+func (a *AdminSettingsService) restoreRedacted(key string, current any, value any) any {
+	root := a.GetSchema()
+	keySchema := schemaForKey(root, key)
+	if a.IsSensitive(key) {
+		keySchema = &schema.Schema{WriteOnly: true}
+	}
+
+	return restoreFields(value, current, keySchema, root)
+}
+
+func restoreFields(value any, current any, _schema *schema.Schema, root *schema.Schema) any {
+	if _schema == nil {
+		return value
+	}
+	if !_schema.WriteOnly {
+		_schema = _schema.Resolve(root)
+		if _schema == nil {
+			return value
+		}
+	}
+	if _schema.WriteOnly {
+		if value == RedactedValue && current != nil {
+			return current
+		}
+		return value
+	}
+
+	switch incoming := value.(type) {
+	case map[string]any:
+		restored := make(map[string]any, len(incoming))
+		for name, entry := range incoming {
+			entryCurrent, _ := internal.LookupPath(current, []string{name})
+			restored[name] = restoreFields(entry, entryCurrent, propertySchema(_schema, name), root)
+		}
+		return restored
+	case []any:
+		restored := make([]any, len(incoming))
+		for i, entry := range incoming {
+			entryCurrent, _ := internal.LookupPath(current, []string{fmt.Sprint(i)})
+			restored[i] = restoreFields(entry, entryCurrent, _schema.Items, root)
+		}
+		return restored
+	}
+
+	return value
+}
+
+// propertySchema returns the schema of a named property, falling back to additionalProperties for maps.
+func propertySchema(_schema *schema.Schema, name string) *schema.Schema {
+	if _schema.Properties != nil {
+		if propSchema, ok := _schema.Properties.Get(name); ok {
+			return propSchema
+		}
+	}
+	return _schema.AdditionalProperties
+}
+
+// RevealSetting returns the unmasked setting and records who revealed it. Only users allowed to change
+// the setting may reveal it.
+func (a *AdminSettingsService) RevealSetting(key string, userID uint) (*messages.SettingsItem, error) {
+	setting := a.GetSetting(key)
+	if setting == nil {
+		return nil, ErrSettingNotFound
+	}
+
+	if !a.CanWrite(userID, key) {
+		return nil, ErrPermissionDenied
+	}
+
+	a.ctx.Logger().Info("sensitive setting revealed",
+		zap.String("key", key),
+		zap.Uint("user_id", userID),
+	)
+
+	return setting, nil
+}
+
+func redactValue(value any) any {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		// An unset secret reveals nothing, and showing it helps spot missing credentials
+		return value
+	}
+	return RedactedValue
+}
+
+func isSensitiveName(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '-'
+	})
+
+	for _, word := range words {
+		for _, sensitive := range sensitiveWords {
+			if word == sensitive {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal/db"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"reflect"
+	"sort"
+)
+
+const (
+	SettingChangeStatusAdded   = "added"
+	SettingChangeStatusRemoved = "removed"
+	SettingChangeStatusChanged = "changed"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrSnapshotExists   = errors.New("snapshot already exists")
+)
+
+// CreateSnapshot saves the current value of every editable setting under the given name.
+func (a *AdminSettingsService) CreateSnapshot(actor uint, name string) (*models.SettingsSnapshot, error) {
+	if _, err := a.GetSnapshot(name); err == nil {
+		return nil, ErrSnapshotExists
+	} else if !errors.Is(err, ErrSnapshotNotFound) {
+		return nil, err
+	}
+
+	snapshot := &models.SettingsSnapshot{
+		Name:      name,
+		Values:    a.editableValues(),
+		CreatedBy: actor,
+	}
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Create(snapshot)
+	}); err != nil {
+		return nil, err
+	}
+
+	a.ctx.Logger().Info("settings snapshot created", zap.String("name", name), zap.Uint("user_id", actor))
+
+	return snapshot, nil
+}
+
+func (a *AdminSettingsService) ListSnapshots() ([]models.SettingsSnapshot, error) {
+	var snapshots []models.SettingsSnapshot
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC").Find(&snapshots)
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (a *AdminSettingsService) GetSnapshot(name string) (*models.SettingsSnapshot, error) {
+	var snapshot models.SettingsSnapshot
+
+	err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Where(&models.SettingsSnapshot{Name: name}).First(&snapshot)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// DiffSnapshot compares a snapshot against another snapshot, or against the live config when other is empty.
+// Old values come from the named snapshot and new values from the comparison target.
+func (a *AdminSettingsService) DiffSnapshot(name string, other string) ([]*messages.SettingsChange, error) {
+	from, err := a.GetSnapshot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var to map[string]any
+	if other == "" {
+		to = a.editableValues()
+	} else {
+		target, err := a.GetSnapshot(other)
+		if err != nil {
+			return nil, err
+		}
+		to = target.Values
+	}
+
+	return a.diffValues(from.Values, to), nil
+}
+
+// RestoreSnapshot applies the snapshot's values to the running config. Only editable keys the actor may
+// change are restored, and the rest are reported as skipped or failed.
+func (a *AdminSettingsService) RestoreSnapshot(actor uint, name string, dryRun bool) ([]*messages.SettingsChange, error) {
+	snapshot, err := a.GetSnapshot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := a.applySettings(actor, snapshot.Values, dryRun, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		a.ctx.Logger().Info("settings snapshot restored", zap.String("name", name), zap.Uint("user_id", actor))
+	}
+
+	return changes, nil
+}
+
+// editableValues returns the current value of every editable setting in a form that survives JSON storage,
+// with structs keyed by their config names so a restore normalizes them back.
+func (a *AdminSettingsService) editableValues() map[string]any {
+	values := make(map[string]any)
+	for key, value := range a.ctx.Config().All() {
+		if a.ctx.Config().IsEditable(key) {
+			values[key] = internal.EncodeSetting(value)
+		}
+	}
+	return values
+}
+
+func (a *AdminSettingsService) diffValues(from, to map[string]any) []*messages.SettingsChange {
+	keys := make(map[string]struct{}, len(from)+len(to))
+	for key := range from {
+		keys[key] = struct{}{}
+	}
+	for key := range to {
+		keys[key] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	changes := make([]*messages.SettingsChange, 0)
+	for _, key := range sorted {
+		oldValue, inFrom := from[key]
+		newValue, inTo := to[key]
+
+		change := &messages.SettingsChange{
+			Key:      key,
+			OldValue: a.RedactValue(key, oldValue),
+			NewValue: a.RedactValue(key, newValue),
+			Editable: a.ctx.Config().IsEditable(key),
+		}
+
+		switch {
+		case !inFrom:
+			change.Status = SettingChangeStatusAdded
+		case !inTo:
+			change.Status = SettingChangeStatusRemoved
+		case !jsonEqual(oldValue, newValue):
+			change.Status = SettingChangeStatusChanged
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// jsonEqual compares values by their JSON form, so stored snapshot values compare equal to live ones.
+func jsonEqual(a, b any) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	var aValue, bValue any
+	if json.Unmarshal(aJSON, &aValue) != nil || json.Unmarshal(bJSON, &bValue) != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return reflect.DeepEqual(aValue, bValue)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestRestoreUnchangedSnapshot stores a snapshot the way the snapshot model does and checks restoring it
+// over the same config finds nothing to change.
+func TestRestoreUnchangedSnapshot(t *testing.T) {
+	a := newTestSettingsService(map[string]any{
+		"core.domain":        "example.com",
+		"core.db.timeout":    90 * time.Second,
+		"core.storage.s3":    scheduledTestBackend{Name: "primary", Credentials: scheduledTestCredentials{AccessKey: "key"}},
+		"core.storage.pools": []scheduledTestBackend{{Name: "hot", Timeout: time.Minute}, {Name: "cold"}},
+	}, nil)
+
+	data, err := json.Marshal(a.editableValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored map[string]any
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := a.applySettings(0, stored, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		t.Errorf("%s: status %s, error %q", change.Key, change.Status, change.Error)
+	}
+
+	if diff := a.diffValues(stored, a.editableValues()); len(diff) != 0 {
+		t.Errorf("snapshot differs from the live config: %d changes", len(diff))
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Struct tags config fields can use to document themselves in the settings schema.
+const (
+	descriptionTag = "description"
+	titleTag       = "title"
+	enumTag        = "enum"
+	minimumTag     = "minimum"
+	maximumTag     = "maximum"
+	patternTag     = "pattern"
+	formatTag      = "format"
+	exampleTag     = "example"
+	deprecatedTag  = "deprecated"
+)
+
+// applyFieldTags fills in schema metadata from the struct tags of a config field. Enum values and
+// examples are converted to the field's type, and numeric bounds that do not parse are ignored.
+func applyFieldTags(_schema *schema.Schema, field reflect.StructField) {
+	tag := field.Tag
+
+	if v := tag.Get(titleTag); v != "" {
+		_schema.Title = v
+	}
+	if v := tag.Get(descriptionTag); v != "" {
+		_schema.Description = v
+	}
+	if v := tag.Get(patternTag); v != "" {
+		_schema.Pattern = v
+	}
+	if v := tag.Get(formatTag); v != "" {
+		_schema.Format = v
+	}
+	if v := tag.Get(minimumTag); v != "" {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			_schema.Minimum = json.Number(v)
+		}
+	}
+	if v := tag.Get(maximumTag); v != "" {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			_schema.Maximum = json.Number(v)
+		}
+	}
+	if v, err := strconv.ParseBool(tag.Get(deprecatedTag)); err == nil {
+		_schema.Deprecated = v
+	}
+
+	kind := tagValueKind(field.Type)
+
+	if v := tag.Get(enumTag); v != "" {
+		for _, option := range strings.Split(v, ",") {
+			_schema.Enum = append(_schema.Enum, parseTagValue(kind, strings.TrimSpace(option)))
+		}
+	}
+	if v, ok := tag.Lookup(exampleTag); ok {
+		_schema.Examples = append(_schema.Examples, parseTagValue(kind, v))
+	}
+}
+
+// tagValueKind returns the kind tag values should be converted to, looking through pointers and
+// at the element type of slices so enums can constrain list entries.
+func tagValueKind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind()
+}
+
+func parseTagValue(kind reflect.Kind, value string) any {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/schema"
+	"go.lumeweb.com/portal/config"
+	"go.lumeweb.com/portal/core"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testContext provides just enough of the portal context for the settings service: a config manager
+// backed by a map of flattened keys.
+type testContext struct {
+	core.Context
+	config *testConfig
+}
+
+func newTestContext(values map[string]any) *testContext {
+	return &testContext{config: &testConfig{values: values}}
+}
+
+func (c *testContext) Config() config.Manager {
+	return c.config
+}
+
+type testConfig struct {
+	config.Manager
+
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+func (c *testConfig) All() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	all := make(map[string]any, len(c.values))
+	for key, value := range c.values {
+		all[key] = value
+	}
+	return all
+}
+
+func (c *testConfig) Get(key string) any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.values[key]
+}
+
+func (c *testConfig) Exists(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for existing := range c.values {
+		if existing == key || strings.HasPrefix(existing, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *testConfig) IsEditable(string) bool {
+	return true
+}
+
+func (c *testConfig) set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value == nil {
+		delete(c.values, key)
+		return
+	}
+	c.values[key] = value
+}
+
+// newTestSettingsService returns a service over the values using the given schema state.
+func newTestSettingsService(values map[string]any, state *settingsSchema) *AdminSettingsService {
+	a := &AdminSettingsService{
+		ctx:             newTestContext(values),
+		pendingRestarts: make(map[string]*pendingRestart),
+		subscribers:     make(map[chan *messages.SettingsEvent]struct{}),
+		runtimeEdits:    make(map[string]any),
+	}
+	if state == nil {
+		state = &settingsSchema{schema: &schema.Schema{Type: "object"}}
+	}
+	a.schemaState.Store(state)
+	// Checked in the future, so the schema is never rebuilt from the config structs the fake lacks
+	a.schemaCheckedAt.Store(time.Now().Add(time.Hour).UnixNano())
+	return a
+}
+
+// SchemaTestListener is exported so it is promoted like the embedded structs of the portal config. Its
+// name has a different type from the server's, so the test can tell which one the schema describes.
+type SchemaTestListener struct {
+	Name int    `config:"name"`
+	Port uint16 `config:"port"`
+}
+
+type schemaTestServer struct {
+	Name string `config:"name"`
+	SchemaTestListener
+	Weight int `config:"weight"`
+}
+
+func TestStructSchemaShadowedFields(t *testing.T) {
+	builder := &schemaBuilder{
+		schema:     &schema.Schema{Type: "object"},
+		ctx:        newTestContext(nil),
+		sensitive:  make(map[string]bool),
+		restart:    make(map[string]bool),
+		defaults:   make(map[string]any),
+		visiting:   make(map[reflect.Type]bool),
+		structUses: make(map[reflect.Type][]*schema.Schema),
+	}
+
+	serverSchema := builder.getStructSchema(reflect.TypeFor[schemaTestServer](), "core.server")
+
+	var names []string
+	for pair := serverSchema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		names = append(names, pair.Key)
+	}
+	if want := []string{"name", "port", "weight"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("properties = %v, want %v", names, want)
+	}
+
+	name, _ := serverSchema.Properties.Get("name")
+	direct := builder.getElemSchema(reflect.TypeFor[string](), reflect.Value{}, "core.server.name")
+	if name.Type != direct.Type {
+		t.Fatalf("name has type %q, want the direct field's %q", name.Type, direct.Type)
+	}
+}
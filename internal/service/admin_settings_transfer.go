@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"go.lumeweb.com/portal-plugin-admin/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"reflect"
+	"sort"
+)
+
+const (
+	SettingChangeStatusPending   = "pending"
+	SettingChangeStatusApplied   = "applied"
+	SettingChangeStatusSkipped   = "skipped"
+	SettingChangeStatusUnchanged = "unchanged"
+	SettingChangeStatusError     = "error"
+)
+
+// ExportSettings returns the running config as a nested document matching the config file layout.
+// Sensitive values are masked, and values are encoded the way the config file writes them so the document
+// imports back unchanged.
+func (a *AdminSettingsService) ExportSettings() map[string]any {
+	all := a.ctx.Config().All()
+	for key, value := range all {
+		all[key] = internal.EncodeSetting(a.RedactValue(key, value))
+	}
+	return internal.NestSettings(all)
+}
+
+// ImportSettings diffs the document against the running config and, unless dryRun is set, applies the
+// changed editable keys. Keys that are unknown or not editable are reported as skipped, and masked
+// sensitive values from an export are left untouched. A non-empty version must match GetSettingsVersion.
+func (a *AdminSettingsService) ImportSettings(actor uint, doc map[string]any, dryRun bool, version string) ([]*messages.SettingsChange, error) {
+	return a.applySettings(actor, internal.FlattenSettings(doc), dryRun, version)
+}
+
+// applySettings diffs flat key/value pairs against the running config and applies the changed keys
+// unless dryRun is set. Changes are validated before updateMu is taken, and a change whose setting is
+// modified in the meantime fails with ErrVersionMismatch. A non-empty version must match
+// GetSettingsVersion both before validating and when applying.
+func (a *AdminSettingsService) applySettings(actor uint, flat map[string]any, dryRun bool, version string) ([]*messages.SettingsChange, error) {
+	if !VersionMatches(version, a.GetSettingsVersion()) {
+		return nil, ErrVersionMismatch
+	}
+
+	access := a.AccessFor(actor)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := make([]*messages.SettingsChange, 0, len(keys))
+	writes := make(map[*messages.SettingsChange]*settingWrite)
+	for _, key := range keys {
+		change, write := a.diffSetting(access, key, flat[key])
+		if change.Status == SettingChangeStatusUnchanged {
+			continue
+		}
+		if write != nil {
+			writes[change] = write
+		}
+		changes = append(changes, change)
+	}
+
+	if !dryRun && len(writes) > 0 {
+		a.updateMu.Lock()
+		defer a.updateMu.Unlock()
+
+		if !VersionMatches(version, a.GetSettingsVersion()) {
+			return nil, ErrVersionMismatch
+		}
+
+		for _, change := range changes {
+			if write, ok := writes[change]; ok {
+				if err := a.commitWrite(actor, write); err != nil {
+					setChangeError(change, err)
+				} else {
+					change.Status = SettingChangeStatusApplied
+				}
+			}
+		}
+	}
+
+	for _, change := range changes {
+		change.OldValue = a.RedactValue(change.Key, change.OldValue)
+		change.NewValue = a.RedactValue(change.Key, change.NewValue)
+	}
+
+	return changes, nil
+}
+
+// setChangeError marks the change as failed. Validation errors keep the message of every check that
+// rejected the value, the same way a single update reports them.
+func setChangeError(change *messages.SettingsChange, err error) {
+	change.Status = SettingChangeStatusError
+	change.Error = err.Error()
+
+	var invalid *SettingValidationError
+	if errors.As(err, &invalid) {
+		change.Error = ErrSettingInvalid.Error()
+		change.Errors = invalid.Errors
+	}
+}
+
+// diffSetting compares the value against the running config and, if it changes an editable setting the
+// actor may write, validates it and returns the pending write.
+func (a *AdminSettingsService) diffSetting(access *SettingsAccess, key string, value any) (*messages.SettingsChange, *settingWrite) {
+	change := &messages.SettingsChange{
+		Key:      key,
+		NewValue: value,
+	}
+
+	if !a.ctx.Config().Exists(key) {
+		change.Status = SettingChangeStatusSkipped
+		change.Error = ErrSettingNotFound.Error()
+		return change, nil
+	}
+
+	current := a.ctx.Config().Get(key)
+	change.OldValue = current
+	change.Editable = a.ctx.Config().IsEditable(key)
+
+	value = a.restoreRedacted(key, current, value)
+	normalized, err := a.NormalizeSetting(key, current, value)
+	if err != nil {
+		setChangeError(change, err)
+		return change, nil
+	}
+	change.NewValue = normalized
+
+	if reflect.DeepEqual(current, normalized) {
+		change.Status = SettingChangeStatusUnchanged
+		return change, nil
+	}
+
+	if !change.Editable {
+		change.Status = SettingChangeStatusSkipped
+		change.Error = ErrSettingNotEditable.Error()
+		return change, nil
+	}
+
+	if !access.CanWrite(key) {
+		change.Status = SettingChangeStatusSkipped
+		change.Error = ErrPermissionDenied.Error()
+		return change, nil
+	}
+
+	if err := a.checkSettingLock(key); err != nil {
+		change.Status = SettingChangeStatusSkipped
+		change.Error = err.Error()
+		return change, nil
+	}
+
+	if access.Actor() != 0 && a.RequiresApproval(key) {
+		change.Status = SettingChangeStatusSkipped
+		change.Error = ErrApprovalRequired.Error()
+		return change, nil
+	}
+
+	write, err := a.validateWrite(&messages.SettingsItem{Key: key, Value: normalized})
+	if err != nil {
+		setChangeError(change, err)
+		return change, nil
+	}
+
+	change.Status = SettingChangeStatusPending
+	return change, write
+}
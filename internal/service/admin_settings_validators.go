@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/internal"
+	"go.lumeweb.com/portal-plugin-admin/settings"
+	"strings"
+	"time"
+)
+
+// settingValidatorTimeout bounds how long the validators of a single change may run, since they may
+// reach out to other services.
+const settingValidatorTimeout = 10 * time.Second
+
+var ErrSettingInvalid = errors.New("setting is invalid")
+
+// SettingValidationError holds the messages of every registered validator that rejected a change.
+type SettingValidationError struct {
+	Key    string
+	Errors []string
+}
+
+func (e *SettingValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, strings.Join(e.Errors, "; "))
+}
+
+func (e *SettingValidationError) Is(target error) bool {
+	return target == ErrSettingInvalid
+}
+
+// runValidators runs the validators registered for the changed key. When the change rebuilds a larger
+// setting, such as a list for an element update, the validators of that setting run on the whole value too.
+func (a *AdminSettingsService) runValidators(key string, baseKey string, value any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), settingValidatorTimeout)
+	defer cancel()
+
+	if key != baseKey {
+		element, _ := internal.LookupPath(value, strings.Split(key[len(baseKey)+1:], "."))
+		if err := runSettingValidators(ctx, key, element); err != nil {
+			return err
+		}
+	}
+
+	return runSettingValidators(ctx, baseKey, value)
+}
+
+func runSettingValidators(ctx context.Context, key string, value any) error {
+	var messages []string
+	for _, validator := range settings.ValidatorsFor(key) {
+		if err := callValidator(ctx, validator, key, value); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return &SettingValidationError{Key: key, Errors: messages}
+	}
+	return nil
+}
+
+// callValidator keeps a misbehaving validator from another plugin from taking down the request.
+func callValidator(ctx context.Context, validator settings.Validator, key string, value any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("validator failed: %v", r)
+		}
+	}()
+
+	return validator(ctx, key, value)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-admin/internal/db/models"
+	"go.lumeweb.com/portal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+)
+
+// settingsVersionKeyID is the primary key of the single SettingsVersionKey row.
+const settingsVersionKeyID = 1
+
+// versionKey keys the version hash so versions of sensitive settings cannot be used to guess their values.
+// It is loaded from the database once at startup, before any request is served.
+var versionKey []byte
+
+// loadVersionKey reads the shared version key, creating it on first start. When several nodes start at once
+// only the first insert wins and every node reads the same row back.
+func (a *AdminSettingsService) loadVersionKey() error {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+
+	row := &models.SettingsVersionKey{Key: hex.EncodeToString(random)}
+	row.ID = settingsVersionKeyID
+
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	}); err != nil {
+		return err
+	}
+
+	var stored models.SettingsVersionKey
+	if err := db.RetryOnLock(a.db, func(db *gorm.DB) *gorm.DB {
+		return db.First(&stored, settingsVersionKeyID)
+	}); err != nil {
+		return err
+	}
+
+	key, err := hex.DecodeString(stored.Key)
+	if err != nil {
+		return fmt.Errorf("invalid settings version key: %w", err)
+	}
+
+	versionKey = key
+	return nil
+}
+
+// SettingVersion derives an opaque version for a setting value, suitable for use as an ETag. Versions
+// only change with the value, and are the same across restarts and cluster nodes.
+func SettingVersion(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", value))
+	}
+
+	mac := hmac.New(sha256.New, versionKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// GetSettingsVersion derives a version covering every setting, changing whenever any value changes. It only
+// reads the running config, so it stays cheap enough to call while holding updateMu.
+func (a *AdminSettingsService) GetSettingsVersion() string {
+	all := a.ctx.Config().All()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(SettingVersion(all[key])))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+// VersionMatches compares an If-Match style header against a version. An empty expectation or "*"
+// always matches, and both quoted and weak ETags are accepted.
+func VersionMatches(expected, version string) bool {
+	if expected == "" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(expected, ",") {
+		candidate = strings.TrimSpace(candidate)
+		candidate = strings.TrimPrefix(candidate, "W/")
+		candidate = strings.Trim(candidate, `"`)
+		if candidate == "*" || candidate == version {
+			return true
+		}
+	}
+
+	return false
+}
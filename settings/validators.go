@@ -0,0 +1,60 @@
+// Package settings lets other portal plugins hook into changes made through the admin settings API.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Validator checks the value a setting is about to be changed to, after it has been converted to the
+// setting's type. Returning an error rejects the change and the error message is shown to the admin.
+type Validator func(ctx context.Context, key string, value any) error
+
+type registeredValidator struct {
+	pattern   string
+	validator Validator
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []registeredValidator
+)
+
+// RegisterValidator runs the validator for every setting key matching the pattern. Patterns are dotted
+// keys where "*" matches a single segment, such as "core.mail.*" or "core.storage.s3.endpoint".
+func RegisterValidator(pattern string, validator Validator) error {
+	if validator == nil {
+		return fmt.Errorf("validator for %q is nil", pattern)
+	}
+	if _, err := path.Match(toPath(pattern), ""); err != nil {
+		return fmt.Errorf("invalid validator pattern %q: %w", pattern, err)
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	validators = append(validators, registeredValidator{pattern: pattern, validator: validator})
+	return nil
+}
+
+// ValidatorsFor returns the validators whose pattern matches the key, in registration order.
+func ValidatorsFor(key string) []Validator {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	matched := make([]Validator, 0)
+	for _, registered := range validators {
+		if ok, _ := path.Match(toPath(registered.pattern), toPath(key)); ok {
+			matched = append(matched, registered.validator)
+		}
+	}
+	return matched
+}
+
+// toPath turns a dotted key into a slash separated one, so path.Match wildcards stop at segment boundaries.
+func toPath(key string) string {
+	return strings.ReplaceAll(key, ".", "/")
+}